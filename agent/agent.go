@@ -9,6 +9,8 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 	"time"
+
+	"github.com/isucon/isucandar/metrics"
 )
 
 var (
@@ -57,6 +59,19 @@ type Agent struct {
 	DefaultAccept string
 	CacheStore    CacheStore
 	HttpClient    *http.Client
+	// nil でない場合、リクエストごとの時間を記録します
+	Metrics *metrics.Registry
+	// メトリクスやエラー、リクエストログでパスをまとめるのに使われます
+	Routes        *RouteRegistry
+	RequestLogger RequestLogger
+	// nil でない場合、送受信した内容を HAR として記録します
+	Recorder *Recorder
+	// nil の場合は再試行しません
+	RetryPolicy *RetryPolicy
+	// nil でない場合、キャッシュから応答できないリクエストの送信頻度を制限します
+	RateLimiter *RateLimiter
+
+	connections connectionCounter
 }
 
 func NewAgent(opts ...AgentOption) (*Agent, error) {
@@ -67,6 +82,7 @@ func NewAgent(opts ...AgentOption) (*Agent, error) {
 		BaseURL:       nil,
 		DefaultAccept: DefaultAccept,
 		CacheStore:    NewCacheStore(),
+		Routes:        NewRouteRegistry(),
 		HttpClient: &http.Client{
 			CheckRedirect: useLastResponse,
 			Transport:     DefaultTransport,
@@ -91,12 +107,67 @@ func (a *Agent) ClearCookie() {
 	}
 }
 
-func (a *Agent) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+func (a *Agent) Do(ctx context.Context, req *http.Request, opts ...RequestOption) (*http.Response, error) {
+	config, err := newRequestConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(config.headers) > 0 {
+		req = req.Clone(ctx)
+		for key, values := range config.headers {
+			req.Header.Del(key)
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+
+	started := time.Now()
+	res, cached, attempts, err := a.doWithRetry(ctx, req, config)
+
+	statusCode := 0
+	if res != nil {
+		statusCode = res.StatusCode
+		if err = config.checkStatus(res); err != nil {
+			res = nil
+		}
+	}
+
+	route := a.Route(req)
+	if err != nil {
+		err = &RequestError{Method: req.Method, Route: route, Attempts: attempts, Err: err}
+	}
+
+	if a.RequestLogger != nil {
+		entry := RequestLogEntry{
+			Method:     req.Method,
+			URL:        req.URL.String(),
+			Route:      route,
+			StatusCode: statusCode,
+			Duration:   time.Since(started),
+			Cached:     cached,
+			Attempts:   attempts,
+			Err:        err,
+		}
+		a.RequestLogger(entry)
+	}
+
+	return res, err
+}
+
+func (a *Agent) do(ctx context.Context, req *http.Request, config *requestConfig) (*http.Response, bool, error) {
+	ctx = a.connections.trace(ctx)
 	req = req.WithContext(ctx)
 
+	cacheStore := a.CacheStore
+	if config.noCache {
+		cacheStore = nil
+	}
+
 	var cache *Cache
-	if a.CacheStore != nil {
-		cache = a.CacheStore.Get(req)
+	if cacheStore != nil {
+		cache = cacheStore.Get(req)
 	}
 	if cache != nil {
 		cache.apply(req)
@@ -105,30 +176,96 @@ func (a *Agent) Do(ctx context.Context, req *http.Request) (*http.Response, erro
 	var res *http.Response
 	var err error
 
-	if cache != nil && !cache.requiresRevalidate(req) {
+	var rec *harRecording
+	if a.Recorder != nil {
+		rec = a.Recorder.begin(req, a.Route(req))
+	}
+
+	var tracer *requestTracer
+	cached := cache != nil && !cache.requiresRevalidate(req)
+	if cached {
 		res = cache.restoreResponse()
 	} else {
-		res, err = a.HttpClient.Do(req)
+		if a.RateLimiter != nil {
+			if err := a.RateLimiter.Wait(ctx); err != nil {
+				if rec != nil {
+					rec.fail(err)
+				}
+				return nil, false, err
+			}
+		}
+
+		if a.Metrics != nil || rec != nil {
+			var traceCtx context.Context
+			traceCtx, tracer = newRequestTracer(ctx)
+			req = req.WithContext(traceCtx)
+		}
+
+		res, err = config.client(a.HttpClient).Do(req)
 		if err != nil {
-			return nil, err
+			if a.Metrics != nil {
+				a.Metrics.RecordError(a.metricsKey(req))
+			}
+			if rec != nil {
+				rec.fail(err)
+			}
+			return nil, false, err
+		}
+
+		if rec != nil {
+			rec.countRaw(res)
 		}
 
 		res, err = decompress(res)
 		if err != nil {
-			return nil, err
+			if rec != nil {
+				rec.fail(err)
+			}
+			return nil, false, err
+		}
+	}
+
+	if rec != nil {
+		rec.response(req, res, cached)
+	}
+
+	var measured *measuredBody
+	if tracer != nil || rec != nil {
+		key := a.metricsKey(req)
+		measured = &measuredBody{
+			body: res.Body,
+			record: func() {
+				var timing *metrics.Timing
+				if tracer != nil {
+					t := tracer.finish()
+					timing = &t
+					if a.Metrics != nil {
+						a.Metrics.Record(key, t)
+					}
+				}
+				if rec != nil {
+					rec.finish(timing)
+				}
+			},
 		}
+		res.Body = measured
 	}
 
 	cache, err = newCache(res, cache.Body())
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+
+	// 304 の場合はキャッシュされたボディに差し替えられるため、ここで計測を終えます
+	if measured != nil && cache != nil && res.StatusCode == http.StatusNotModified {
+		measured.Close()
 	}
 
-	if cache != nil && a.CacheStore != nil {
-		a.CacheStore.Put(req, cache)
+	if cache != nil && cacheStore != nil {
+		cacheStore.Put(req, cache)
 	}
 
-	return res, nil
+	return res, cached, nil
 }
 
 func (a *Agent) NewRequest(method string, target string, body io.Reader) (*http.Request, error) {
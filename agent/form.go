@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+)
+
+// application/x-www-form-urlencoded のボディを持つリクエストを作ります
+func (a *Agent) NewFormRequest(method string, target string, values url.Values) (*http.Request, error) {
+	req, err := a.NewRequest(method, target, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+func (a *Agent) FormPOST(target string, values url.Values) (*http.Request, error) {
+	return a.NewFormRequest(http.MethodPost, target, values)
+}
+
+type PartOption func(textproto.MIMEHeader)
+
+func WithPartHeader(key, value string) PartOption {
+	return func(h textproto.MIMEHeader) {
+		h.Set(key, value)
+	}
+}
+
+func WithPartContentType(contentType string) PartOption {
+	return WithPartHeader("Content-Type", contentType)
+}
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// MultipartForm は multipart/form-data のボディを組み立てます。
+// 各パートは追加した時点で読み込まれるため、同じフォームから何度でもリクエストを作れます。
+type MultipartForm struct {
+	parts []*multipartPart
+}
+
+func NewMultipartForm() *MultipartForm {
+	return &MultipartForm{
+		parts: []*multipartPart{},
+	}
+}
+
+func (f *MultipartForm) AddField(name, value string, opts ...PartOption) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, quoteEscaper.Replace(name)))
+	f.add(header, []byte(value), opts)
+}
+
+// Content-Type を指定しない場合、ファイル名の拡張子か内容から推測します
+func (f *MultipartForm) AddFileBytes(field, filename string, data []byte, opts ...PartOption) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(field), quoteEscaper.Replace(filename)))
+
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	header.Set("Content-Type", contentType)
+
+	f.add(header, data, opts)
+}
+
+func (f *MultipartForm) AddFileReader(field, filename string, r io.Reader, opts ...PartOption) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	f.AddFileBytes(field, filename, data, opts...)
+	return nil
+}
+
+// path のファイルを読み込み、ファイル名を filename としてパートに追加します
+func (f *MultipartForm) AddFilePath(field, path string, opts ...PartOption) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return f.AddFileReader(field, filepath.Base(path), file, opts...)
+}
+
+func (f *MultipartForm) add(header textproto.MIMEHeader, body []byte, opts []PartOption) {
+	for _, opt := range opts {
+		opt(header)
+	}
+
+	f.parts = append(f.parts, &multipartPart{
+		header: header,
+		body:   body,
+	})
+}
+
+// ボディと、boundary を含む Content-Type を返します
+func (f *MultipartForm) Encode() ([]byte, string, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+
+	for _, part := range f.parts {
+		pw, err := w.CreatePart(part.header)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := pw.Write(part.body); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// multipart/form-data のボディを持ち、Content-Type と Content-Length が設定されたリクエストを作ります
+func (a *Agent) NewMultipartRequest(method string, target string, form *MultipartForm) (*http.Request, error) {
+	body, contentType, err := form.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := a.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	return req, nil
+}
+
+func (a *Agent) MultipartPOST(target string, form *MultipartForm) (*http.Request, error) {
+	return a.NewMultipartRequest(http.MethodPost, target, form)
+}
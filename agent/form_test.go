@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAgentForm(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" || r.ContentLength <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.ParseForm()
+		w.Write([]byte(r.PostForm.Get("name") + "," + strings.Join(r.PostForm["tag"], ",")))
+	}))
+	defer srv.Close()
+
+	agent, err := NewAgent(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := agent.FormPOST("/", url.Values{"name": {"isu con"}, "tag": {"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := agent.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || string(body) != "isu con,a,b" {
+		t.Fatal(res.StatusCode, string(body))
+	}
+}
+
+func TestAgentMultipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Length") != strconv.FormatInt(r.ContentLength, 10) || r.ContentLength <= 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		result := []string{r.FormValue("title")}
+		for _, field := range []string{"image", "text", "file"} {
+			fh := r.MultipartForm.File[field][0]
+			f, _ := fh.Open()
+			data, _ := ioutil.ReadAll(f)
+			f.Close()
+			result = append(result, fh.Filename, fh.Header.Get("Content-Type"), string(data))
+		}
+		result = append(result, r.MultipartForm.File["text"][0].Header.Get("X-Part"))
+		w.Write([]byte(strings.Join(result, "|")))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "isucandar-form")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "note.txt")
+	if err := ioutil.WriteFile(path, []byte("from disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	form := NewMultipartForm()
+	form.AddField("title", "hello")
+	form.AddFileBytes("image", `a"b.png`, []byte("\x89PNG\r\n\x1a\n"))
+	if err := form.AddFileReader("text", "data", strings.NewReader("plain text"), WithPartHeader("X-Part", "1"), WithPartContentType("text/x-custom")); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.AddFilePath("file", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.AddFilePath("file", filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("missing file added")
+	}
+
+	agent, err := NewAgent(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		req, err := agent.MultipartPOST("/", form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data; boundary=") || req.ContentLength <= 0 {
+			t.Fatal(req.Header, req.ContentLength)
+		}
+
+		res, err := agent.Do(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		expected := strings.Join([]string{
+			"hello",
+			`a"b.png`, "image/png", "\x89PNG\r\n\x1a\n",
+			"data", "text/x-custom", "plain text",
+			"note.txt", "text/plain; charset=utf-8", "from disk",
+			"1",
+		}, "|")
+		if res.StatusCode != http.StatusOK || string(body) != expected {
+			t.Fatalf("%d %q", res.StatusCode, body)
+		}
+	}
+}
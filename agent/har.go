@@ -0,0 +1,574 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/isucon/isucandar/metrics"
+)
+
+var (
+	DefaultRecorderBodyLimit = 64 * 1024
+)
+
+type HAR struct {
+	Log *HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator *HARCreator `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time    `json:"startedDateTime"`
+	Time            float64      `json:"time"`
+	Request         *HARRequest  `json:"request"`
+	Response        *HARResponse `json:"response"`
+	Cache           *HARCache    `json:"cache"`
+	Timings         *HARTimings  `json:"timings"`
+	// Agent.Route で正規化されたパスと、エラーが発生した場合はそのメッセージが入ります
+	Comment string `json:"comment,omitempty"`
+}
+
+// 記録中のエントリはロックを取って更新されるため、ロックの外で参照する場合はこのコピーを用います
+func (e *HAREntry) clone() *HAREntry {
+	entry := *e
+	if e.Request != nil {
+		request := *e.Request
+		request.Cookies = cloneNameValues(e.Request.Cookies)
+		request.Headers = cloneNameValues(e.Request.Headers)
+		request.QueryString = cloneNameValues(e.Request.QueryString)
+		if e.Request.PostData != nil {
+			postData := *e.Request.PostData
+			request.PostData = &postData
+		}
+		entry.Request = &request
+	}
+	if e.Response != nil {
+		response := *e.Response
+		response.Cookies = cloneNameValues(e.Response.Cookies)
+		response.Headers = cloneNameValues(e.Response.Headers)
+		if e.Response.Content != nil {
+			content := *e.Response.Content
+			response.Content = &content
+		}
+		entry.Response = &response
+	}
+	if e.Cache != nil {
+		cache := *e.Cache
+		entry.Cache = &cache
+	}
+	if e.Timings != nil {
+		timings := *e.Timings
+		entry.Timings = &timings
+	}
+	return &entry
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARRequest struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*HARNameValue `json:"cookies"`
+	Headers     []*HARNameValue `json:"headers"`
+	QueryString []*HARNameValue `json:"queryString"`
+	PostData    *HARPostData    `json:"postData,omitempty"`
+	HeadersSize int64           `json:"headersSize"`
+	BodySize    int64           `json:"bodySize"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	// テキストとして扱えないボディは "base64" でエンコードされます
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Body は Encoding に従ってデコードしたボディを返します
+func (p *HARPostData) Body() ([]byte, error) {
+	return decodeHARText(p.Text, p.Encoding)
+}
+
+type HARResponse struct {
+	Status      int             `json:"status"`
+	StatusText  string          `json:"statusText"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*HARNameValue `json:"cookies"`
+	Headers     []*HARNameValue `json:"headers"`
+	Content     *HARContent     `json:"content"`
+	RedirectURL string          `json:"redirectURL"`
+	HeadersSize int64           `json:"headersSize"`
+	BodySize    int64           `json:"bodySize"`
+}
+
+type HARContent struct {
+	Size int64 `json:"size"`
+	// 圧縮によって削減されたバイト数です
+	Compression int64  `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text"`
+	// テキストとして扱えないボディは "base64" でエンコードされます
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Body は Encoding に従ってデコードしたボディを返します
+func (c *HARContent) Body() ([]byte, error) {
+	return decodeHARText(c.Text, c.Encoding)
+}
+
+type HARCache struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Recorder は Agent が送受信したリクエストとレスポンスを HAR 1.2 形式で記録します。
+// ボディは BodyLimit バイトまで保存されます。
+type Recorder struct {
+	BodyLimit int
+
+	mu      sync.RWMutex
+	entries []*HAREntry
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		BodyLimit: DefaultRecorderBodyLimit,
+		mu:        sync.RWMutex{},
+		entries:   []*HAREntry{},
+	}
+}
+
+func (r *Recorder) Entries() []*HAREntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]*HAREntry, len(r.entries))
+	for i, entry := range r.entries {
+		entries[i] = entry.clone()
+	}
+	return entries
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = []*HAREntry{}
+}
+
+func (r *Recorder) HAR() *HAR {
+	return &HAR{
+		Log: &HARLog{
+			Version: "1.2",
+			Creator: &HARCreator{
+				Name:    DefaultName,
+				Version: "1.0",
+			},
+			Entries: r.Entries(),
+		},
+	}
+}
+
+func (r *Recorder) WriteHAR(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r.HAR())
+}
+
+// 1リクエスト分の記録中の状態です
+type harRecording struct {
+	recorder *Recorder
+	entry    *HAREntry
+	started  time.Time
+	reqBody  *limitedBuffer
+	resBody  *limitedBuffer
+	rawSize  *int64
+
+	decompressed bool
+}
+
+func (r *Recorder) begin(req *http.Request, route string) *harRecording {
+	rec := &harRecording{
+		recorder: r,
+		started:  time.Now(),
+		entry: &HAREntry{
+			Request: &HARRequest{
+				Method:      req.Method,
+				URL:         req.URL.String(),
+				HTTPVersion: req.Proto,
+				Cookies:     harCookies(req.Cookies()),
+				Headers:     harHeaders(req.Header),
+				QueryString: []*HARNameValue{},
+				HeadersSize: -1,
+				BodySize:    -1,
+			},
+			Cache:   &HARCache{},
+			Timings: &HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+			Comment: route,
+		},
+	}
+	rec.entry.StartedDateTime = rec.started
+
+	for key, values := range req.URL.Query() {
+		for _, value := range values {
+			rec.entry.Request.QueryString = append(rec.entry.Request.QueryString, &HARNameValue{Name: key, Value: value})
+		}
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		rec.reqBody = newLimitedBuffer(r.BodyLimit)
+		req.Body = &teeBody{body: req.Body, w: rec.reqBody}
+	} else {
+		rec.entry.Request.BodySize = 0
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, rec.entry)
+	r.mu.Unlock()
+
+	return rec
+}
+
+// 圧縮された状態のボディのサイズを数えます
+func (rec *harRecording) countRaw(res *http.Response) {
+	size := int64(0)
+	rec.rawSize = &size
+	res.Body = &countingBody{body: res.Body, n: rec.rawSize}
+}
+
+func (rec *harRecording) response(req *http.Request, res *http.Response, cached bool) {
+	rec.resBody = newLimitedBuffer(rec.recorder.BodyLimit)
+	res.Body = &teeBody{body: res.Body, w: rec.resBody}
+
+	rec.recorder.mu.Lock()
+	defer rec.recorder.mu.Unlock()
+
+	// Cookie は http.Client が送信時に追加するため、送信後のヘッダーで上書きします
+	rec.entry.Request.Headers = harHeaders(req.Header)
+	rec.entry.Request.Cookies = harCookies(req.Cookies())
+	rec.fillRequestBody()
+
+	content := &HARContent{
+		Size:     -1,
+		MimeType: res.Header.Get("Content-Type"),
+	}
+	if encoding := res.Header.Get("Content-Encoding"); encoding != "" && res.Uncompressed {
+		rec.decompressed = true
+		content.Comment = "decompressed from " + encoding
+	}
+
+	rec.entry.Response = &HARResponse{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: res.Proto,
+		Cookies:     harCookies(res.Cookies()),
+		Headers:     harHeaders(res.Header),
+		Content:     content,
+		RedirectURL: res.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+
+	if cached {
+		rec.entry.Cache.Comment = "hit"
+		rec.entry.Response.BodySize = 0
+	} else if res.StatusCode == http.StatusNotModified {
+		rec.entry.Cache.Comment = "revalidated"
+	}
+}
+
+func (rec *harRecording) fail(err error) {
+	rec.recorder.mu.Lock()
+	defer rec.recorder.mu.Unlock()
+
+	rec.fillRequestBody()
+	rec.entry.Time = msec(time.Since(rec.started))
+	if rec.entry.Response == nil {
+		rec.entry.Response = &HARResponse{
+			Cookies: []*HARNameValue{},
+			Headers: []*HARNameValue{},
+			Content: &HARContent{},
+		}
+	}
+	rec.entry.Comment += ": " + err.Error()
+}
+
+func (rec *harRecording) finish(timing *metrics.Timing) {
+	rec.recorder.mu.Lock()
+	defer rec.recorder.mu.Unlock()
+
+	if timing != nil {
+		rec.entry.Time = msec(timing.Total)
+		rec.entry.Timings = harTimings(*timing)
+	} else {
+		rec.entry.Time = msec(time.Since(rec.started))
+		rec.entry.Timings = &HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	}
+
+	if rec.entry.Response == nil || rec.resBody == nil {
+		return
+	}
+
+	content := rec.entry.Response.Content
+	content.Size = rec.resBody.Size()
+	content.Text, content.Encoding = encodeHARText(rec.resBody.Bytes(), content.MimeType)
+	if rec.resBody.truncated() {
+		content.Comment = joinComment(content.Comment, "truncated")
+	}
+
+	if rec.rawSize != nil {
+		rawSize := atomic.LoadInt64(rec.rawSize)
+		rec.entry.Response.BodySize = rawSize
+		if rec.decompressed && rawSize < content.Size {
+			content.Compression = content.Size - rawSize
+		}
+	}
+}
+
+func (rec *harRecording) fillRequestBody() {
+	if rec.reqBody == nil || rec.entry.Request.PostData != nil {
+		return
+	}
+
+	rec.entry.Request.BodySize = rec.reqBody.Size()
+	mimeType := headerValue(rec.entry.Request.Headers, "Content-Type")
+	text, encoding := encodeHARText(rec.reqBody.Bytes(), mimeType)
+	rec.entry.Request.PostData = &HARPostData{
+		MimeType: mimeType,
+		Text:     text,
+		Encoding: encoding,
+	}
+	if rec.reqBody.truncated() {
+		rec.entry.Request.PostData.Comment = "truncated"
+	}
+}
+
+func harTimings(t metrics.Timing) *HARTimings {
+	timings := &HARTimings{
+		Blocked: -1,
+		DNS:     -1,
+		Connect: -1,
+		SSL:     -1,
+		Send:    0,
+		Wait:    msec(t.TTFB - t.DNS - t.Connect - t.TLS),
+		Receive: msec(t.Total - t.TTFB),
+	}
+	if t.DNS > 0 {
+		timings.DNS = msec(t.DNS)
+	}
+	if t.Connect > 0 {
+		// HAR の connect は TLS ハンドシェイクの時間を含みます
+		timings.Connect = msec(t.Connect + t.TLS)
+	}
+	if t.TLS > 0 {
+		timings.SSL = msec(t.TLS)
+	}
+	if timings.Wait < 0 {
+		timings.Wait = 0
+	}
+	if timings.Receive < 0 {
+		timings.Receive = 0
+	}
+	return timings
+}
+
+func harHeaders(header http.Header) []*HARNameValue {
+	headers := []*HARNameValue{}
+	for key, values := range header {
+		for _, value := range values {
+			headers = append(headers, &HARNameValue{Name: key, Value: value})
+		}
+	}
+	return headers
+}
+
+func harCookies(cookies []*http.Cookie) []*HARNameValue {
+	values := make([]*HARNameValue, 0, len(cookies))
+	for _, cookie := range cookies {
+		values = append(values, &HARNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+	return values
+}
+
+func cloneNameValues(values []*HARNameValue) []*HARNameValue {
+	if values == nil {
+		return nil
+	}
+
+	cloned := make([]*HARNameValue, len(values))
+	for i, value := range values {
+		v := *value
+		cloned[i] = &v
+	}
+	return cloned
+}
+
+// JSON の文字列では不正な UTF-8 が置き換えられてしまうため、テキスト以外のボディは base64 で保存します
+func encodeHARText(body []byte, mimeType string) (string, string) {
+	if utf8.Valid(body) && isTextMimeType(mimeType) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func decodeHARText(text, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return []byte(text), nil
+}
+
+// MIME タイプが不明な場合は、UTF-8 として正しいボディをテキストとみなします
+func isTextMimeType(mimeType string) bool {
+	if mimeType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/ecmascript", "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
+
+func headerValue(headers []*HARNameValue, name string) string {
+	for _, header := range headers {
+		if http.CanonicalHeaderKey(header.Name) == name {
+			return header.Value
+		}
+	}
+	return ""
+}
+
+func joinComment(comment, addition string) string {
+	if comment == "" {
+		return addition
+	}
+	return comment + ", " + addition
+}
+
+func msec(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// limit バイトまでを保存し、全体のサイズも数えるバッファです
+type limitedBuffer struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	limit int
+	size  int64
+}
+
+func newLimitedBuffer(limit int) *limitedBuffer {
+	return &limitedBuffer{limit: limit}
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.size += int64(len(p))
+	if rest := b.limit - b.buf.Len(); rest > 0 {
+		if len(p) > rest {
+			b.buf.Write(p[:rest])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+func (b *limitedBuffer) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size
+}
+
+func (b *limitedBuffer) truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size > int64(b.buf.Len())
+}
+
+type teeBody struct {
+	body io.ReadCloser
+	w    io.Writer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	if n > 0 {
+		t.w.Write(p[:n])
+	}
+	return n, err
+}
+
+func (t *teeBody) Close() error {
+	return t.body.Close()
+}
+
+type countingBody struct {
+	body io.ReadCloser
+	n    *int64
+}
+
+func (c *countingBody) Read(p []byte) (int, error) {
+	n, err := c.body.Read(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
+
+func (c *countingBody) Close() error {
+	return c.body.Close()
+}
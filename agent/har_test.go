@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := ioutil.ReadAll(r.Body)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write(body)
+		case "/cached":
+			w.Header().Set("Cache-Control", "max-age=100")
+			io.WriteString(w, "cached body")
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			gw := gzip.NewWriter(w)
+			defer gw.Close()
+			io.WriteString(gw, strings.Repeat("a", 1000))
+		}
+	}))
+	defer srv.Close()
+
+	recorder := NewRecorder()
+	recorder.BodyLimit = 100
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(req *http.Request) {
+		res, err := agent.Do(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	req, _ := agent.POST("/echo?q=1", strings.NewReader("hello"))
+	req.Header.Set("Content-Type", "text/plain")
+	do(req)
+	req, _ = agent.GET("/echo")
+	do(req)
+	req, _ = agent.GET("/cached")
+	do(req)
+	req, _ = agent.GET("/cached")
+	do(req)
+	req, _ = agent.GET("/gzip")
+	do(req)
+
+	entries := recorder.Entries()
+	if len(entries) != 5 {
+		t.Fatal(len(entries))
+	}
+
+	post := entries[0]
+	if post.Request.Method != http.MethodPost || post.Request.PostData == nil || post.Request.PostData.Text != "hello" || post.Request.PostData.MimeType != "text/plain" {
+		t.Fatalf("%+v", post.Request)
+	}
+	if len(post.Request.QueryString) != 1 || post.Request.QueryString[0].Value != "1" {
+		t.Fatalf("%+v", post.Request.QueryString)
+	}
+	if post.Response.Status != 200 || post.Response.Content.Text != "hello" || post.Response.Content.Size != 5 || len(post.Response.Cookies) != 1 {
+		t.Fatalf("%+v", post.Response)
+	}
+	if post.Time <= 0 || post.Timings.Wait < 0 || post.Comment != "/echo" {
+		t.Fatalf("%+v", post)
+	}
+
+	if cookies := entries[1].Request.Cookies; len(cookies) != 1 || cookies[0].Value != "abc" {
+		t.Fatalf("%+v", cookies)
+	}
+
+	if entries[2].Cache.Comment != "" || entries[3].Cache.Comment != "hit" || entries[3].Response.Content.Text != "cached body" {
+		t.Fatalf("%+v %+v", entries[2].Cache, entries[3].Cache)
+	}
+
+	content := entries[4].Response.Content
+	if content.Size != 1000 || len(content.Text) != 100 || content.Compression <= 0 || !strings.Contains(content.Comment, "gzip") || !strings.Contains(content.Comment, "truncated") {
+		t.Fatalf("%+v", content)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := recorder.WriteHAR(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	har := &HAR{}
+	if err := json.Unmarshal(buf.Bytes(), har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 5 {
+		t.Fatalf("%+v", har.Log)
+	}
+
+	recorder.Reset()
+	if len(recorder.Entries()) != 0 {
+		t.Fatal("not reset")
+	}
+}
+
+func TestRecorderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	recorder := NewRecorder()
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := agent.GET("/items/1")
+	if _, err := agent.Do(context.Background(), req); err == nil {
+		t.Fatal("request to closed server succeeded")
+	}
+
+	entries := recorder.Entries()
+	if len(entries) != 1 || entries[0].Response.Status != 0 || !strings.HasPrefix(entries[0].Comment, "/items/:id: ") {
+		t.Fatalf("%+v", entries)
+	}
+}
+
+func TestRecorderWriteHARWhileRecording(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "body")
+	}))
+	defer srv.Close()
+
+	recorder := NewRecorder()
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithRecorder(recorder), WithNoCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			req, _ := agent.GET("/")
+			res, err := agent.Do(context.Background(), req)
+			if err != nil {
+				return
+			}
+			ioutil.ReadAll(res.Body)
+			res.Body.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			if entries := recorder.Entries(); len(entries) != 20 || entries[19].Response.Content.Text != "body" {
+				t.Fatalf("%+v", entries)
+			}
+			return
+		default:
+			if err := recorder.WriteHAR(ioutil.Discard); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestRecorderBinaryBody(t *testing.T) {
+	png := []byte{0x89, 0x50, 0x4e, 0x47, 0xff, 0xfe, 0x00, 0x01}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	}))
+	defer srv.Close()
+
+	recorder := NewRecorder()
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithRecorder(recorder), WithNoCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := agent.POST("/upload", bytes.NewReader(png))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res, err := agent.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	buf := &bytes.Buffer{}
+	if err := recorder.WriteHAR(buf); err != nil {
+		t.Fatal(err)
+	}
+	har := &HAR{}
+	if err := json.NewDecoder(buf).Decode(har); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := har.Log.Entries[0]
+	if content := entry.Response.Content; content.Encoding != "base64" {
+		t.Fatalf("%+v", content)
+	}
+	if body, err := entry.Response.Content.Body(); err != nil || !bytes.Equal(body, png) {
+		t.Fatal(body, err)
+	}
+	if body, err := entry.Request.PostData.Body(); err != nil || !bytes.Equal(body, png) || entry.Request.PostData.Encoding != "base64" {
+		t.Fatal(body, err)
+	}
+}
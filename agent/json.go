@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+var (
+	ErrInvalidJSON failure.StringCode = "invalid-json"
+
+	DefaultJSONBodyLimit int64 = 10 * 1024 * 1024
+	// エラーメッセージに含めるボディの最大バイト数
+	JSONErrorSnippetSize = 256
+)
+
+const (
+	jsonContentType = "application/json"
+)
+
+// JSONDecodeError は JSON として解釈できなかったレスポンスボディの先頭部分を保持します
+type JSONDecodeError struct {
+	Err     error
+	Snippet string
+}
+
+func (e *JSONDecodeError) Error() string {
+	return fmt.Sprintf("%s (body: %q)", e.Err.Error(), e.Snippet)
+}
+
+func (e *JSONDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// v を JSON にエンコードしたボディを持つリクエストを作ります。v が nil の場合はボディを持ちません。
+func (a *Agent) NewJSONRequest(method string, target string, v interface{}) (*http.Request, error) {
+	var body io.Reader
+	if v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := a.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if v != nil {
+		req.Header.Set("Content-Type", jsonContentType)
+	}
+	req.Header.Set("Accept", jsonContentType)
+
+	return req, nil
+}
+
+func (a *Agent) JSONGET(target string) (*http.Request, error) {
+	return a.NewJSONRequest(http.MethodGet, target, nil)
+}
+
+func (a *Agent) JSONPOST(target string, v interface{}) (*http.Request, error) {
+	return a.NewJSONRequest(http.MethodPost, target, v)
+}
+
+func (a *Agent) JSONPUT(target string, v interface{}) (*http.Request, error) {
+	return a.NewJSONRequest(http.MethodPut, target, v)
+}
+
+func (a *Agent) JSONPATCH(target string, v interface{}) (*http.Request, error) {
+	return a.NewJSONRequest(http.MethodPatch, target, v)
+}
+
+// リクエストを送信し、レスポンスボディを DefaultJSONBodyLimit バイトまで読んで out にデコードします。
+// ボディは読み終えた時点で閉じられます。
+func (a *Agent) DoJSON(ctx context.Context, req *http.Request, out interface{}, opts ...RequestOption) (*http.Response, error) {
+	res, err := a.Do(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := DecodeJSON(res, out, DefaultJSONBodyLimit); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// res のボディを limit バイトまで読み込んで v にデコードし、ボディを閉じます。
+// limit を超える場合やデコードに失敗した場合は ErrInvalidJSON のエラーを返します。
+func DecodeJSON(res *http.Response, v interface{}, limit int64) error {
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		return err
+	}
+
+	if int64(len(body)) > limit {
+		return failure.NewError(ErrInvalidJSON, &JSONDecodeError{
+			Err:     fmt.Errorf("response body exceeds %d bytes", limit),
+			Snippet: snippet(body),
+		})
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return failure.NewError(ErrInvalidJSON, &JSONDecodeError{
+			Err:     err,
+			Snippet: snippet(body),
+		})
+	}
+
+	return nil
+}
+
+func snippet(body []byte) string {
+	if len(body) <= JSONErrorSnippetSize {
+		return string(body)
+	}
+
+	// マルチバイト文字の途中で切らないようにします
+	end := JSONErrorSnippetSize
+	for end > 0 && !utf8.RuneStart(body[end]) {
+		end--
+	}
+	return string(body[:end]) + "..."
+}
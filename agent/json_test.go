@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+type jsonItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestAgentJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/items":
+			if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Accept") != "application/json" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			item := &jsonItem{}
+			json.NewDecoder(r.Body).Decode(item)
+			item.ID = 1
+			json.NewEncoder(w).Encode(item)
+		case "/broken":
+			io.WriteString(w, "<html>"+strings.Repeat("あ", 200)+"</html>")
+		case "/large":
+			io.WriteString(w, `"`+strings.Repeat("a", 100)+`"`)
+		}
+	}))
+	defer srv.Close()
+
+	agent, err := NewAgent(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := agent.JSONPOST("/items", &jsonItem{Name: "isucon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := &jsonItem{}
+	res, err := agent.DoJSON(context.Background(), req, item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || item.ID != 1 || item.Name != "isucon" {
+		t.Fatal(res.StatusCode, item)
+	}
+
+	req, _ = agent.JSONGET("/broken")
+	if req.Header.Get("Content-Type") != "" || req.Body != nil {
+		t.Fatal(req.Header)
+	}
+	_, err = agent.DoJSON(context.Background(), req, item)
+	if !failure.IsCode(err, ErrInvalidJSON) {
+		t.Fatal(err)
+	}
+	var jerr *JSONDecodeError
+	if !errors.As(err, &jerr) || !strings.HasPrefix(jerr.Snippet, "<html>") || !strings.HasSuffix(jerr.Snippet, "...") || len(jerr.Snippet) > JSONErrorSnippetSize+3 {
+		t.Fatal(err)
+	}
+
+	req, _ = agent.JSONGET("/large")
+	res, err = agent.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err := DecodeJSON(res, &s, 10); !failure.IsCode(err, ErrInvalidJSON) || !strings.Contains(fmt.Sprint(err), "exceeds 10 bytes") {
+		t.Fatal(err)
+	}
+
+	if _, err := agent.JSONPOST("/items", make(chan int)); err == nil {
+		t.Fatal("unsupported value encoded")
+	}
+}
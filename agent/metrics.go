@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucandar/metrics"
+)
+
+var (
+	numericSegmentRegexp = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegmentRegexp    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// 数値や UUID のパスセグメントを :id に置き換えてエンドポイントを集約します
+func NormalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if numericSegmentRegexp.MatchString(segment) || uuidSegmentRegexp.MatchString(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (a *Agent) metricsKey(req *http.Request) string {
+	return req.Method + " " + a.Route(req)
+}
+
+type requestTracer struct {
+	mu      sync.Mutex
+	start   time.Time
+	dns     time.Time
+	connect time.Time
+	tls     time.Time
+	timing  metrics.Timing
+}
+
+func newRequestTracer(ctx context.Context) (context.Context, *requestTracer) {
+	t := &requestTracer{
+		mu:    sync.Mutex{},
+		start: time.Now(),
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(_ httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Now()
+		},
+		DNSDone: func(_ httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timing.DNS = time.Since(t.dns)
+		},
+		ConnectStart: func(_, _ string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connect = time.Now()
+		},
+		ConnectDone: func(_, _ string, _ error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timing.Connect = time.Since(t.connect)
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tls = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, _ error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timing.TLS = time.Since(t.tls)
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.timing.TTFB = time.Since(t.start)
+		},
+	}
+
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+func (t *requestTracer) finish() metrics.Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.timing.Total = time.Since(t.start)
+	return t.timing
+}
+
+// レスポンスボディを読み終えるか Close した時点で時間を記録します
+type measuredBody struct {
+	body   io.ReadCloser
+	once   sync.Once
+	record func()
+}
+
+func (b *measuredBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.record)
+	}
+	return n, err
+}
+
+func (b *measuredBody) Close() error {
+	b.once.Do(b.record)
+	return b.body.Close()
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/metrics"
+)
+
+func TestNormalizePath(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/":                     "/",
+		"/items/123":            "/items/:id",
+		"/items/123/comments/4": "/items/:id/comments/:id",
+		"/users/alice":          "/users/alice",
+		"/e/0f8fad5b-d9cb-469f-a165-70867728950e": "/e/:id",
+	} {
+		if actual := NormalizePath(path); actual != expected {
+			t.Fatalf("%s: %s", path, actual)
+		}
+	}
+}
+
+func TestAgentMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	registry := metrics.NewRegistry()
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithMetrics(registry), WithNoCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/items/1", "/items/2"} {
+		req, _ := agent.GET(path)
+		res, err := agent.Do(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	endpoint, found := registry.Endpoint("GET /items/:id")
+	if !found {
+		t.Fatal(registry.Keys())
+	}
+
+	summary := endpoint.Summary()
+	if summary.Count != 2 || summary.Connect.Count != 1 || summary.TTFB.Min < 5*time.Millisecond || summary.Total.Max < summary.TTFB.Min {
+		t.Fatalf("%+v", summary)
+	}
+
+	srv.Close()
+	req, _ := agent.GET("/items/3")
+	if _, err := agent.Do(context.Background(), req); err == nil {
+		t.Fatal("request to closed server succeeded")
+	}
+	if endpoint.Errors() != 1 {
+		t.Fatal(endpoint.Errors())
+	}
+}
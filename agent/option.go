@@ -3,6 +3,8 @@ package agent
 import (
 	"net/url"
 	"time"
+
+	"github.com/isucon/isucandar/metrics"
 )
 
 func WithNoCookie() AgentOption {
@@ -40,3 +42,50 @@ func WithTimeout(d time.Duration) AgentOption {
 		return nil
 	}
 }
+
+func WithMetrics(registry *metrics.Registry) AgentOption {
+	return func(a *Agent) error {
+		a.Metrics = registry
+		return nil
+	}
+}
+
+func WithRoutes(templates ...string) AgentOption {
+	return func(a *Agent) error {
+		for _, template := range templates {
+			if err := a.Routes.Add("", template); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// 複数のエージェントで同じルート定義を共有する場合に使います
+func WithRouteRegistry(registry *RouteRegistry) AgentOption {
+	return func(a *Agent) error {
+		a.Routes = registry
+		return nil
+	}
+}
+
+func WithRequestLogger(logger RequestLogger) AgentOption {
+	return func(a *Agent) error {
+		a.RequestLogger = logger
+		return nil
+	}
+}
+
+func WithRecorder(recorder *Recorder) AgentOption {
+	return func(a *Agent) error {
+		a.Recorder = recorder
+		return nil
+	}
+}
+
+func WithRetryPolicy(policy *RetryPolicy) AgentOption {
+	return func(a *Agent) error {
+		a.RetryPolicy = policy
+		return nil
+	}
+}
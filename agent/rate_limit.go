@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidRate = errors.New("rate must be positive")
+)
+
+// RateLimiter はトークンバケットで単位時間あたりの量を制限します。
+// 毎秒 rate 個のトークンが補充され、最大 burst 個まで貯められます。
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(rate float64, burst int) (*RateLimiter, error) {
+	if rate <= 0 {
+		return nil, ErrInvalidRate
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		mu:     sync.Mutex{},
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}, nil
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(l.last)
+	l.last = now
+	l.tokens = math.Min(l.burst, l.tokens+elapsed.Seconds()*l.rate)
+}
+
+// トークンが1つ以上あれば消費して true を返します
+func (l *RateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// n 個のトークンを予約し、補充されるまで待ちます。
+// n が burst より大きい場合も、不足分が補充されるまで待つことで消費できます。
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	l.mu.Lock()
+	l.refill(time.Now())
+	l.tokens -= float64(n)
+	wait := time.Duration(0)
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens += float64(n)
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func WithRateLimit(rate float64, burst int) AgentOption {
+	return func(a *Agent) error {
+		limiter, err := NewRateLimiter(rate, burst)
+		if err != nil {
+			return err
+		}
+		a.RateLimiter = limiter
+		return nil
+	}
+}
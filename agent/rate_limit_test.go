@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if _, err := NewRateLimiter(0, 1); err != ErrInvalidRate {
+		t.Fatal(err)
+	}
+
+	limiter, err := NewRateLimiter(100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !limiter.Allow() || !limiter.Allow() || limiter.Allow() {
+		t.Fatal("burst not respected")
+	}
+
+	started := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(started); elapsed < 40*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := limiter.WaitN(ctx, 100); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "OK")
+	}))
+	defer srv.Close()
+
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithRateLimit(50, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	for i := 0; i < 4; i++ {
+		doAndDiscard(t, agent, "/")
+	}
+	if elapsed := time.Since(started); elapsed < 60*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	if _, err := NewAgent(WithRateLimit(-1, 1)); err != ErrInvalidRate {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentBandwidthLimit(t *testing.T) {
+	payload := bytes.Repeat([]byte("a"), 20*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if len(body) > 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write(payload)
+	}))
+	defer srv.Close()
+
+	// 下り 100KiB/s で 20KiB を受信すると 150ms 以上かかる (バースト分を除く)
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithNoCache(), WithBandwidthLimit(0, 100*1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent.HttpClient.Transport == DefaultTransport {
+		t.Fatal("default transport modified")
+	}
+
+	started := time.Now()
+	res := doAndDiscard(t, agent, "/")
+	if res.StatusCode != http.StatusOK {
+		t.Fatal(res.StatusCode)
+	}
+	if elapsed := time.Since(started); elapsed < 150*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	// 上り 100KiB/s で 20KiB を送信する
+	uploader, err := NewAgent(WithBaseURL(srv.URL), WithBandwidthLimit(100*1024, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader.HttpClient.Timeout = 5 * time.Second
+
+	started = time.Now()
+	req, _ := uploader.POST("/", bytes.NewReader(payload))
+	res, err = uploader.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if elapsed := time.Since(started); elapsed < 150*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	// 独自の RoundTripper は置き換えない
+	custom := http.NewFileTransport(http.Dir("."))
+	agent.HttpClient.Transport = custom
+	if err := WithBandwidthLimit(0, 100*1024)(agent); err != ErrUnsupportedTransport {
+		t.Fatal(err)
+	}
+	if agent.HttpClient.Transport != custom {
+		t.Fatal("custom transport replaced")
+	}
+}
+
+func TestThrottledConnDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(ioutil.Discard, server)
+
+	// 毎秒 10 バイトでは 100 バイトの送信に 10 秒かかる
+	limiter, _ := NewRateLimiter(10, 1)
+	conn := newThrottledConn(client, limiter, nil, 1)
+
+	conn.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+	started := time.Now()
+	if _, err := conn.Write(bytes.Repeat([]byte("a"), 100)); err == nil {
+		t.Fatal("write succeeded after deadline")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatal(elapsed)
+	}
+
+	conn.SetWriteDeadline(time.Time{})
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn.Close()
+	}()
+	started = time.Now()
+	if _, err := conn.Write(bytes.Repeat([]byte("a"), 100)); err == nil {
+		t.Fatal("write succeeded after close")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatal(elapsed)
+	}
+}
@@ -0,0 +1,20 @@
+package agent
+
+import (
+	"time"
+)
+
+type RequestLogEntry struct {
+	Method string
+	URL    string
+	// Agent.Route で正規化されたパス
+	Route      string
+	StatusCode int
+	Duration   time.Duration
+	// キャッシュから応答し、リクエストを送信しなかった場合に true になります
+	Cached   bool
+	Attempts int
+	Err      error
+}
+
+type RequestLogger func(RequestLogEntry)
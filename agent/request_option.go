@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+var (
+	ErrUnexpectedStatus failure.StringCode = "unexpected-status"
+)
+
+type RequestOption func(*requestConfig) error
+
+// Agent.Do の1回の呼び出しに限って適用される設定です
+type requestConfig struct {
+	timeout        time.Duration
+	hasTimeout     bool
+	noCache        bool
+	noCookie       bool
+	headers        http.Header
+	expectedStatus []int
+}
+
+func newRequestConfig(opts []RequestOption) (*requestConfig, error) {
+	config := &requestConfig{
+		headers:        http.Header{},
+		expectedStatus: []int{},
+	}
+
+	for _, opt := range opts {
+		if err := opt(config); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// HttpClient を書き換えずに設定を反映するため、必要な場合は複製したクライアントを返します
+func (c *requestConfig) client(base *http.Client) *http.Client {
+	if !c.hasTimeout && !c.noCookie {
+		return base
+	}
+
+	client := *base
+	if c.hasTimeout {
+		client.Timeout = c.timeout
+	}
+	if c.noCookie {
+		client.Jar = nil
+	}
+	return &client
+}
+
+func (c *requestConfig) checkStatus(res *http.Response) error {
+	if len(c.expectedStatus) == 0 {
+		return nil
+	}
+
+	for _, code := range c.expectedStatus {
+		if res.StatusCode == code {
+			return nil
+		}
+	}
+
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	return failure.NewError(ErrUnexpectedStatus, fmt.Errorf("unexpected status code: %d (expected %v)", res.StatusCode, c.expectedStatus))
+}
+
+func WithRequestTimeout(d time.Duration) RequestOption {
+	return func(c *requestConfig) error {
+		c.timeout = d
+		c.hasTimeout = true
+		return nil
+	}
+}
+
+func WithRequestNoCache() RequestOption {
+	return func(c *requestConfig) error {
+		c.noCache = true
+		return nil
+	}
+}
+
+func WithRequestNoCookie() RequestOption {
+	return func(c *requestConfig) error {
+		c.noCookie = true
+		return nil
+	}
+}
+
+func WithRequestHeader(key, value string) RequestOption {
+	return func(c *requestConfig) error {
+		c.headers.Add(key, value)
+		return nil
+	}
+}
+
+// レスポンスのステータスコードが codes のいずれでもない場合、
+// ボディを閉じて ErrUnexpectedStatus のエラーを返します
+func WithExpectedStatus(codes ...int) RequestOption {
+	return func(c *requestConfig) error {
+		c.expectedStatus = append(c.expectedStatus, codes...)
+		return nil
+	}
+}
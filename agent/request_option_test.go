@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+func TestRequestOptions(t *testing.T) {
+	count := int32(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		case "/cookie":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		case "/cached":
+			w.Header().Set("Cache-Control", "max-age=100")
+		}
+		w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+		w.Header().Set("X-Echo", r.Header.Get("X-Test"))
+		io.WriteString(w, "OK")
+	}))
+	defer srv.Close()
+
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := agent.GET("/slow")
+	_, err = agent.Do(context.Background(), req)
+	var nerr net.Error
+	if !failure.As(err, &nerr) || !nerr.Timeout() {
+		t.Fatal(err)
+	}
+
+	req, _ = agent.GET("/slow")
+	res, err := agent.Do(context.Background(), req, WithRequestTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if agent.HttpClient.Timeout != 10*time.Millisecond {
+		t.Fatal(agent.HttpClient.Timeout)
+	}
+
+	req, _ = agent.GET("/cookie")
+	res, _ = agent.Do(context.Background(), req)
+	res.Body.Close()
+
+	req, _ = agent.GET("/echo")
+	res, _ = agent.Do(context.Background(), req, WithRequestNoCookie(), WithRequestHeader("X-Test", "value"))
+	res.Body.Close()
+	if res.Header.Get("X-Cookie") != "" || res.Header.Get("X-Echo") != "value" || req.Header.Get("X-Test") != "" {
+		t.Fatal(res.Header)
+	}
+
+	req, _ = agent.GET("/echo")
+	res, _ = agent.Do(context.Background(), req)
+	res.Body.Close()
+	if res.Header.Get("X-Cookie") != "session=abc" {
+		t.Fatal(res.Header)
+	}
+
+	atomic.StoreInt32(&count, 0)
+	for i := 0; i < 2; i++ {
+		req, _ = agent.GET("/cached")
+		res, _ = agent.Do(context.Background(), req, WithRequestNoCache())
+		res.Body.Close()
+	}
+	if c := atomic.LoadInt32(&count); c != 2 {
+		t.Fatal(c)
+	}
+}
+
+func TestRequestExpectedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	entries := []RequestLogEntry{}
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithRequestLogger(func(entry RequestLogEntry) {
+		entries = append(entries, entry)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := agent.GET("/")
+	res, err := agent.Do(context.Background(), req, WithExpectedStatus(http.StatusOK, http.StatusNoContent))
+	if res != nil || !failure.IsCode(err, ErrUnexpectedStatus) {
+		t.Fatal(res, err)
+	}
+	if len(entries) != 1 || entries[0].StatusCode != http.StatusNotFound {
+		t.Fatalf("%+v", entries)
+	}
+
+	res, err = agent.Do(context.Background(), req, WithExpectedStatus(http.StatusNotFound))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
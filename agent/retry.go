@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+	"github.com/isucon/isucandar/random"
+)
+
+var (
+	idempotentMethods = map[string]bool{
+		http.MethodGet:     true,
+		http.MethodHead:    true,
+		http.MethodOptions: true,
+		http.MethodTrace:   true,
+		http.MethodPut:     true,
+		http.MethodDelete:  true,
+	}
+)
+
+type RetryPolicy struct {
+	// 最初のリクエストを含めた最大試行回数
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// 待ち時間を ±Jitter の割合でランダムにずらします
+	Jitter float64
+	// これらのステータスコードが返された場合に再試行します
+	StatusCodes []int
+	// タイムアウトや接続のリセットなど一時的なネットワークエラーと、failure.TemporaryErrorCode のエラーで再試行します
+	RetryNetworkErrors bool
+	// POST や PATCH など冪等でないメソッドも再試行します
+	NonIdempotent bool
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:        3,
+		BaseDelay:          100 * time.Millisecond,
+		MaxDelay:           2 * time.Second,
+		Jitter:             0.2,
+		StatusCodes:        []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryNetworkErrors: true,
+		NonIdempotent:      false,
+	}
+}
+
+// attempt 回目の試行が失敗した後に待つ時間を返します
+func (p *RetryPolicy) Backoff(ctx context.Context, attempt int) time.Duration {
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay *= 1 - p.Jitter + 2*p.Jitter*random.FromContext(ctx).Float64()
+	}
+	return time.Duration(delay)
+}
+
+func (p *RetryPolicy) retryable(req *http.Request, res *http.Response, err error) bool {
+	if !p.NonIdempotent && !idempotentMethods[req.Method] {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		if !p.RetryNetworkErrors {
+			return false
+		}
+		return isTransientError(err)
+	}
+
+	for _, code := range p.StatusCodes {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// TLS の検証エラーや不正なスキームなど、再試行しても結果が変わらないエラーは含みません
+func isTransientError(err error) bool {
+	if failure.IsCode(err, failure.TemporaryErrorCode) {
+		return true
+	}
+	if failure.Is(err, io.EOF) || failure.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if failure.Is(err, syscall.ECONNRESET) || failure.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var nerr net.Error
+	return failure.As(err, &nerr) && nerr.Timeout()
+}
+
+func (a *Agent) doWithRetry(ctx context.Context, req *http.Request, config *requestConfig) (*http.Response, bool, int, error) {
+	policy := a.RetryPolicy
+	attempts := 0
+	for {
+		attempts++
+		res, cached, err := a.do(ctx, req, config)
+
+		if policy == nil || attempts >= policy.MaxAttempts || ctx.Err() != nil || !policy.retryable(req, res, err) {
+			return res, cached, attempts, err
+		}
+
+		timer := time.NewTimer(policy.Backoff(ctx, attempts))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, cached, attempts, err
+		case <-timer.C:
+		}
+
+		next, rerr := rewindRequest(req)
+		if rerr != nil {
+			return res, cached, attempts, err
+		}
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		if a.Metrics != nil {
+			a.Metrics.RecordRetry(a.metricsKey(req))
+		}
+		req = next
+	}
+}
+
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+	return next, nil
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/metrics"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	for attempt, expected := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 300 * time.Millisecond,
+		8: 300 * time.Millisecond,
+	} {
+		if d := policy.Backoff(context.Background(), attempt); d != expected {
+			t.Fatalf("%d: %s", attempt, d)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := policy.Backoff(context.Background(), 1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatal(d)
+		}
+	}
+}
+
+func TestAgentRetry(t *testing.T) {
+	count := int32(0)
+	bodies := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if atomic.AddInt32(&count, 1)%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+
+	registry := metrics.NewRegistry()
+	entries := []RequestLogEntry{}
+	agent, err := NewAgent(
+		WithBaseURL(srv.URL),
+		WithNoCache(),
+		WithRetryPolicy(policy),
+		WithMetrics(registry),
+		WithRequestLogger(func(entry RequestLogEntry) {
+			entries = append(entries, entry)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := agent.GET("/")
+	res, err := agent.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || count != 3 || entries[0].Attempts != 3 {
+		t.Fatal(res.StatusCode, count, entries[0].Attempts)
+	}
+
+	if endpoint, _ := registry.Endpoint("GET /"); endpoint.Retries() != 2 {
+		t.Fatal(endpoint.Retries())
+	}
+
+	// 冪等でないメソッドは再試行しない
+	req, _ = agent.POST("/", strings.NewReader("body"))
+	res, err = agent.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || count != 4 {
+		t.Fatal(res.StatusCode, count)
+	}
+
+	policy.NonIdempotent = true
+	req, _ = agent.POST("/", strings.NewReader("body"))
+	res, err = agent.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || count != 6 || bodies[4] != "body" || bodies[5] != "body" {
+		t.Fatal(res.StatusCode, count, bodies)
+	}
+}
+
+func TestAgentRetryNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+
+	agent, err := NewAgent(WithBaseURL(srv.URL), WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := agent.GET("/")
+	_, err = agent.Do(context.Background(), req)
+
+	var rerr *RequestError
+	if !errors.As(err, &rerr) || rerr.Attempts != 3 || !strings.Contains(err.Error(), "(3 attempts)") {
+		t.Fatal(err)
+	}
+
+	policy.RetryNetworkErrors = false
+	_, err = agent.Do(context.Background(), req)
+	if !errors.As(err, &rerr) || rerr.Attempts != 1 {
+		t.Fatal(err)
+	}
+}
+
+func TestAgentRetryPermanentError(t *testing.T) {
+	count := int32(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+	}))
+	defer srv.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+
+	agent, err := NewAgent(WithBaseURL(strings.Replace(srv.URL, "http://", "https://", 1)), WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := agent.GET("/")
+	_, err = agent.Do(context.Background(), req)
+
+	var rerr *RequestError
+	if !errors.As(err, &rerr) || rerr.Attempts != 1 || atomic.LoadInt32(&count) != 0 {
+		t.Fatal(err)
+	}
+
+	req, _ = agent.NewRequest(http.MethodGet, "ftp://example.com/", nil)
+	_, err = agent.Do(context.Background(), req)
+	if !errors.As(err, &rerr) || rerr.Attempts != 1 {
+		t.Fatal(err)
+	}
+}
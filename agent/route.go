@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidRouteTemplate = errors.New("invalid route template")
+)
+
+type routeMatcher func(path string) bool
+
+type route struct {
+	method  string
+	pattern string
+	match   routeMatcher
+}
+
+// RouteRegistry はリクエストのパスを "/items/:id" のようなルート名に正規化します。
+// 登録順に評価され、最初に一致したルートが使われます。
+type RouteRegistry struct {
+	mu     sync.RWMutex
+	routes []*route
+}
+
+func NewRouteRegistry() *RouteRegistry {
+	return &RouteRegistry{
+		mu:     sync.RWMutex{},
+		routes: []*route{},
+	}
+}
+
+// template には ":name" で1セグメント、"*name" で残りの全てのセグメントに一致するパスを指定します。
+// method が空の場合は全てのメソッドに一致します。
+func (r *RouteRegistry) Add(method, template string) error {
+	match, err := compileRouteTemplate(template)
+	if err != nil {
+		return err
+	}
+
+	r.add(method, template, match)
+	return nil
+}
+
+// expr に一致したパスを name として扱います
+func (r *RouteRegistry) AddRegexp(method, name, expr string) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+
+	r.add(method, name, re.MatchString)
+	return nil
+}
+
+func (r *RouteRegistry) add(method, pattern string, match routeMatcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes = append(r.routes, &route{
+		method:  strings.ToUpper(method),
+		pattern: pattern,
+		match:   match,
+	})
+}
+
+func (r *RouteRegistry) Match(method, path string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, route := range r.routes {
+		if route.method != "" && route.method != method {
+			continue
+		}
+		if route.match(path) {
+			return route.pattern, true
+		}
+	}
+	return "", false
+}
+
+func compileRouteTemplate(template string) (routeMatcher, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, ErrInvalidRouteTemplate
+	}
+
+	segments := strings.Split(template, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "*") && i != len(segments)-1 {
+			return nil, ErrInvalidRouteTemplate
+		}
+	}
+
+	return func(path string) bool {
+		parts := strings.Split(path, "/")
+		for i, segment := range segments {
+			if strings.HasPrefix(segment, "*") {
+				return true
+			}
+			if i >= len(parts) {
+				return false
+			}
+			if strings.HasPrefix(segment, ":") {
+				if parts[i] == "" {
+					return false
+				}
+				continue
+			}
+			if segment != parts[i] {
+				return false
+			}
+		}
+		return len(parts) == len(segments)
+	}, nil
+}
+
+// 登録されたルートに一致しない場合、数値や UUID のセグメントを :id に置き換えたパスを返します
+func (a *Agent) Route(req *http.Request) string {
+	if a.Routes != nil {
+		if pattern, found := a.Routes.Match(req.Method, req.URL.Path); found {
+			return pattern
+		}
+	}
+	return NormalizePath(req.URL.Path)
+}
+
+// RequestError は Agent.Do で発生したエラーにリクエストのルートを付与します
+type RequestError struct {
+	Method string
+	Route  string
+	// 再試行を含めた試行回数
+	Attempts int
+	Err      error
+}
+
+func (e *RequestError) Error() string {
+	if e.Attempts > 1 {
+		return e.Method + " " + e.Route + " (" + strconv.Itoa(e.Attempts) + " attempts): " + e.Err.Error()
+	}
+	return e.Method + " " + e.Route + ": " + e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
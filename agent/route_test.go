@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/isucon/isucandar/failure"
+	"github.com/isucon/isucandar/metrics"
+)
+
+func TestRouteRegistry(t *testing.T) {
+	r := NewRouteRegistry()
+
+	if err := r.Add("", "/items/:id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("post", "/items/:id/comments"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add("", "/static/*path"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddRegexp("", "/users/:name", `^/@[a-z]+$`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		method   string
+		path     string
+		expected string
+		found    bool
+	}{
+		{"GET", "/items/abc", "/items/:id", true},
+		{"GET", "/items/", "", false},
+		{"GET", "/items/abc/comments", "", false},
+		{"POST", "/items/abc/comments", "/items/:id/comments", true},
+		{"GET", "/static/js/app.js", "/static/*path", true},
+		{"GET", "/@alice", "/users/:name", true},
+		{"GET", "/", "", false},
+	} {
+		pattern, found := r.Match(c.method, c.path)
+		if pattern != c.expected || found != c.found {
+			t.Fatalf("%s %s: %s %v", c.method, c.path, pattern, found)
+		}
+	}
+
+	if err := r.Add("", "items"); err != ErrInvalidRouteTemplate {
+		t.Fatal(err)
+	}
+	if err := r.Add("", "/*path/items"); err != ErrInvalidRouteTemplate {
+		t.Fatal(err)
+	}
+	if err := r.AddRegexp("", "broken", "("); err == nil {
+		t.Fatal("invalid regexp accepted")
+	}
+}
+
+func TestAgentRoute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	registry := metrics.NewRegistry()
+	entries := []RequestLogEntry{}
+	agent, err := NewAgent(
+		WithBaseURL(srv.URL),
+		WithRoutes("/users/:name"),
+		WithMetrics(registry),
+		WithRequestLogger(func(entry RequestLogEntry) {
+			entries = append(entries, entry)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/users/alice", "/users/bob", "/items/1"} {
+		req, _ := agent.GET(path)
+		res, err := agent.Do(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	if keys := registry.Keys(); len(keys) != 2 || keys[0] != "GET /items/:id" || keys[1] != "GET /users/:name" {
+		t.Fatal(keys)
+	}
+
+	if len(entries) != 3 || entries[1].Route != "/users/:name" || entries[1].StatusCode != http.StatusNoContent || entries[2].Route != "/items/:id" {
+		t.Fatalf("%+v", entries)
+	}
+
+	srv.Close()
+
+	req, _ := agent.GET("/users/carol")
+	_, err = agent.Do(context.Background(), req)
+
+	var rerr *RequestError
+	if !errors.As(err, &rerr) || rerr.Route != "/users/:name" || !strings.HasPrefix(err.Error(), "GET /users/:name: ") {
+		t.Fatal(err)
+	}
+
+	var nerr net.Error
+	if !failure.As(err, &nerr) {
+		t.Fatal(err)
+	}
+
+	if last := entries[len(entries)-1]; last.Err == nil || last.Route != "/users/:name" {
+		t.Fatalf("%+v", last)
+	}
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupportedTransport = errors.New("transport must be *http.Transport")
+)
+
+// 1回の読み書きで転送するのは、毎秒のバイト数の 1/throttleChunkDivisor までです
+const throttleChunkDivisor = 20
+
+// throttledConn は上り・下りそれぞれのバケットから転送量分のトークンを消費します。
+// トークンの待ち時間はコネクションのデッドラインまでで、Close されると打ち切られます。
+type throttledConn struct {
+	net.Conn
+	upload   *RateLimiter
+	download *RateLimiter
+	chunk    int
+
+	ctx           context.Context
+	cancel        context.CancelFunc
+	mu            sync.Mutex
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func newThrottledConn(conn net.Conn, upload, download *RateLimiter, chunk int) *throttledConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &throttledConn{
+		Conn:     conn,
+		upload:   upload,
+		download: download,
+		chunk:    chunk,
+		ctx:      ctx,
+		cancel:   cancel,
+		mu:       sync.Mutex{},
+	}
+}
+
+// 待ちが打ち切られた場合は、続く読み書きがデッドライン超過やクローズのエラーを返します
+func (c *throttledConn) wait(limiter *RateLimiter, n int, deadline time.Time) {
+	ctx := c.ctx
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	limiter.WaitN(ctx, n)
+}
+
+func (c *throttledConn) Read(p []byte) (int, error) {
+	if c.download == nil {
+		return c.Conn.Read(p)
+	}
+
+	if len(p) > c.chunk {
+		p = p[:c.chunk]
+	}
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.mu.Lock()
+		deadline := c.readDeadline
+		c.mu.Unlock()
+		c.wait(c.download, n, deadline)
+	}
+	return n, err
+}
+
+func (c *throttledConn) Write(p []byte) (int, error) {
+	if c.upload == nil {
+		return c.Conn.Write(p)
+	}
+
+	written := 0
+	for written < len(p) {
+		end := written + c.chunk
+		if end > len(p) {
+			end = len(p)
+		}
+		c.mu.Lock()
+		deadline := c.writeDeadline
+		c.mu.Unlock()
+		c.wait(c.upload, end-written, deadline)
+		n, err := c.Conn.Write(p[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (c *throttledConn) Close() error {
+	c.cancel()
+	return c.Conn.Close()
+}
+
+func (c *throttledConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.writeDeadline = t
+	c.mu.Unlock()
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *throttledConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *throttledConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.writeDeadline = t
+	c.mu.Unlock()
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// upload と download にそれぞれ毎秒のバイト数を指定し、Transport の全コネクションで共有される帯域を制限します。
+// 0 以下を指定した方向は制限しません。
+func WithBandwidth(upload, download int64) TransportOption {
+	return func(t *http.Transport) {
+		var up, down *RateLimiter
+		chunk := 0
+		if upload > 0 {
+			up, _ = NewRateLimiter(float64(upload), int(upload/throttleChunkDivisor))
+			chunk = int(upload / throttleChunkDivisor)
+		}
+		if download > 0 {
+			down, _ = NewRateLimiter(float64(download), int(download/throttleChunkDivisor))
+			if c := int(download / throttleChunkDivisor); chunk == 0 || c < chunk {
+				chunk = c
+			}
+		}
+		if up == nil && down == nil {
+			return
+		}
+		if chunk < 1 {
+			chunk = 1
+		}
+
+		dial := t.DialContext
+		if dial == nil {
+			dial = DefaultDialer.DialContext
+		}
+
+		t.Dial = nil
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return newThrottledConn(conn, up, down, chunk), nil
+		}
+	}
+}
+
+// エージェントの Transport の帯域を制限します。
+// 共有の DefaultTransport を使っている場合は、独立した Transport に置き換えます。
+// *http.Transport 以外の RoundTripper が設定されている場合はエラーを返します。
+func WithBandwidthLimit(upload, download int64) AgentOption {
+	return func(a *Agent) error {
+		var transport *http.Transport
+		switch t := a.HttpClient.Transport.(type) {
+		case nil:
+			transport = NewTransport()
+		case *http.Transport:
+			transport = t
+			if transport == DefaultTransport {
+				transport = NewTransport()
+			}
+		default:
+			return ErrUnsupportedTransport
+		}
+		a.HttpClient.Transport = transport
+		WithBandwidth(upload, download)(transport)
+		return nil
+	}
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+)
+
+var (
+	// 主要なブラウザがホストごとに同時に張るコネクション数です
+	DefaultBrowserConnectionsPerHost = 6
+)
+
+type TransportOption func(*http.Transport)
+
+// DefaultTransport を複製し、他のエージェントとコネクションプールを共有しない Transport を作ります
+func NewTransport(opts ...TransportOption) *http.Transport {
+	transport := DefaultTransport.Clone()
+	for _, opt := range opts {
+		opt(transport)
+	}
+	return transport
+}
+
+func WithMaxConnsPerHost(n int) TransportOption {
+	return func(t *http.Transport) {
+		t.MaxConnsPerHost = n
+		t.MaxIdleConnsPerHost = n
+	}
+}
+
+func WithoutKeepAlives() TransportOption {
+	return func(t *http.Transport) {
+		t.DisableKeepAlives = true
+	}
+}
+
+// HTTP/2 を使わず、常に HTTP/1.1 で通信します
+func WithHTTP1Only() TransportOption {
+	return func(t *http.Transport) {
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		t.TLSClientConfig = cloneTLSConfig(t.TLSClientConfig)
+		t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	}
+}
+
+// TLS で接続できる場合は HTTP/2 を使います
+func WithHTTP2() TransportOption {
+	return func(t *http.Transport) {
+		t.ForceAttemptHTTP2 = true
+		t.TLSNextProto = nil
+		t.TLSClientConfig = cloneTLSConfig(t.TLSClientConfig)
+		t.TLSClientConfig.NextProtos = nil
+	}
+}
+
+func cloneTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		return &tls.Config{}
+	}
+	return config.Clone()
+}
+
+func WithIsolatedTransport(opts ...TransportOption) AgentOption {
+	return func(a *Agent) error {
+		a.HttpClient.Transport = NewTransport(opts...)
+		return nil
+	}
+}
+
+// ブラウザと同様にホストごとに最大6本のコネクションを持つ、独立した Transport を使います
+func WithBrowserTransport(opts ...TransportOption) AgentOption {
+	opts = append([]TransportOption{WithMaxConnsPerHost(DefaultBrowserConnectionsPerHost)}, opts...)
+	return WithIsolatedTransport(opts...)
+}
+
+type ConnectionStats struct {
+	// コネクションを取得したリクエストの数
+	Requests int64
+	// 新たに確立したコネクションの数
+	New int64
+	// 既存のコネクションを再利用した数
+	Reused int64
+	// 再利用したうち、アイドル状態だったコネクションの数
+	Idle int64
+}
+
+func (s ConnectionStats) ReuseRatio() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Reused) / float64(s.Requests)
+}
+
+type connectionCounter struct {
+	requests int64
+	new      int64
+	reused   int64
+	idle     int64
+}
+
+func (c *connectionCounter) trace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			atomic.AddInt64(&c.requests, 1)
+			if info.Reused {
+				atomic.AddInt64(&c.reused, 1)
+			} else {
+				atomic.AddInt64(&c.new, 1)
+			}
+			if info.WasIdle {
+				atomic.AddInt64(&c.idle, 1)
+			}
+		},
+	})
+}
+
+func (a *Agent) ConnectionStats() ConnectionStats {
+	return ConnectionStats{
+		Requests: atomic.LoadInt64(&a.connections.requests),
+		New:      atomic.LoadInt64(&a.connections.new),
+		Reused:   atomic.LoadInt64(&a.connections.reused),
+		Idle:     atomic.LoadInt64(&a.connections.idle),
+	}
+}
+
+func (a *Agent) CloseIdleConnections() {
+	a.HttpClient.CloseIdleConnections()
+}
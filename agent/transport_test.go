@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func doAndDiscard(t *testing.T, agent *Agent, path string) *http.Response {
+	req, err := agent.GET(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := agent.Do(context.Background(), req, WithRequestNoCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	return res
+}
+
+func TestIsolatedTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "OK")
+	}))
+	defer srv.Close()
+
+	a, _ := NewAgent(WithBaseURL(srv.URL), WithBrowserTransport())
+	b, _ := NewAgent(WithBaseURL(srv.URL), WithBrowserTransport())
+	defer a.CloseIdleConnections()
+	defer b.CloseIdleConnections()
+
+	if a.HttpClient.Transport == DefaultTransport || a.HttpClient.Transport == b.HttpClient.Transport {
+		t.Fatal("transport is shared")
+	}
+	if transport := a.HttpClient.Transport.(*http.Transport); transport.MaxConnsPerHost != DefaultBrowserConnectionsPerHost {
+		t.Fatal(transport.MaxConnsPerHost)
+	}
+
+	for i := 0; i < 3; i++ {
+		doAndDiscard(t, a, "/")
+	}
+	doAndDiscard(t, b, "/")
+
+	if stats := a.ConnectionStats(); stats.Requests != 3 || stats.New != 1 || stats.Reused != 2 || stats.ReuseRatio() < 0.6 {
+		t.Fatalf("%+v", stats)
+	}
+	// 別のエージェントのコネクションは再利用されない
+	if stats := b.ConnectionStats(); stats.New != 1 || stats.Reused != 0 {
+		t.Fatalf("%+v", stats)
+	}
+
+	c, _ := NewAgent(WithBaseURL(srv.URL), WithIsolatedTransport(WithoutKeepAlives()))
+	for i := 0; i < 3; i++ {
+		doAndDiscard(t, c, "/")
+	}
+	if stats := c.ConnectionStats(); stats.New != 3 || stats.Reused != 0 {
+		t.Fatalf("%+v", stats)
+	}
+}
+
+func TestBrowserTransportMaxConns(t *testing.T) {
+	current := int32(0)
+	peak := int32(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	agent, _ := NewAgent(WithBaseURL(srv.URL), WithBrowserTransport())
+	defer agent.CloseIdleConnections()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			doAndDiscard(t, agent, "/")
+		}()
+	}
+	wg.Wait()
+
+	if p := atomic.LoadInt32(&peak); p > int32(DefaultBrowserConnectionsPerHost) {
+		t.Fatal(p)
+	}
+	if stats := agent.ConnectionStats(); stats.New > int64(DefaultBrowserConnectionsPerHost) {
+		t.Fatalf("%+v", stats)
+	}
+}
+
+func TestTransportProtocol(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	h1, _ := NewAgent(WithBaseURL(srv.URL), WithIsolatedTransport(WithHTTP1Only()))
+	h2, _ := NewAgent(WithBaseURL(srv.URL), WithIsolatedTransport(WithHTTP2()))
+
+	if res := doAndDiscard(t, h1, "/"); res.ProtoMajor != 1 {
+		t.Fatal(res.Proto)
+	}
+	if res := doAndDiscard(t, h2, "/"); res.ProtoMajor != 2 {
+		t.Fatal(res.Proto)
+	}
+}
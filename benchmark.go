@@ -8,6 +8,7 @@ import (
 
 	"github.com/isucon/isucandar/failure"
 	"github.com/isucon/isucandar/parallel"
+	"github.com/isucon/isucandar/random"
 )
 
 var (
@@ -23,30 +24,38 @@ type BenchmarkErrorHook func(error, *BenchmarkStep)
 type Benchmark struct {
 	mu sync.Mutex
 
-	prepareSteps    []BenchmarkStepFunc
-	loadSteps       []BenchmarkStepFunc
-	validationSteps []BenchmarkStepFunc
+	phases     map[string]*BenchmarkPhase
+	phaseNames []string
 
-	panicRecover   bool
-	prepareTimeout time.Duration
-	loadTimeout    time.Duration
-	ignoreCodes    []failure.Code
-	errorHooks     []BenchmarkErrorHook
+	panicRecover  bool
+	errorPolicies map[string]ErrorPolicy
+	errorHooks    []BenchmarkErrorHook
+	hooks         benchmarkHooks
+	criteria      *Criteria
+	seed          *int64
+
+	progressHooks    []BenchmarkProgressHook
+	progressInterval time.Duration
 }
 
 func NewBenchmark(opts ...BenchmarkOption) (*Benchmark, error) {
 	benchmark := &Benchmark{
-		mu:              sync.Mutex{},
-		prepareSteps:    []BenchmarkStepFunc{},
-		loadSteps:       []BenchmarkStepFunc{},
-		validationSteps: []BenchmarkStepFunc{},
-		panicRecover:    true,
-		prepareTimeout:  time.Duration(0),
-		loadTimeout:     time.Duration(0),
-		ignoreCodes:     []failure.Code{},
-		errorHooks:      []BenchmarkErrorHook{},
+		mu:            sync.Mutex{},
+		phases:        make(map[string]*BenchmarkPhase),
+		phaseNames:    []string{},
+		panicRecover:  true,
+		errorPolicies: make(map[string]ErrorPolicy),
+		errorHooks:    []BenchmarkErrorHook{},
+		hooks:         benchmarkHooks{},
+
+		progressHooks:    []BenchmarkProgressHook{},
+		progressInterval: DefaultProgressInterval,
 	}
 
+	benchmark.addPhase(PhasePrepare, WithPhaseErrorCode(ErrPrepare), WithPhaseSequential())
+	benchmark.addPhase(PhaseLoad, WithPhaseErrorCode(ErrLoad), WithPhaseParallel(), WithPhaseAfter(PhasePrepare))
+	benchmark.addPhase(PhaseValidation, WithPhaseErrorCode(ErrValidation), WithPhaseSequential(), WithPhaseAfter(PhaseLoad))
+
 	for _, opt := range opts {
 		if err := opt(benchmark); err != nil {
 			return nil, err
@@ -61,10 +70,24 @@ func (b *Benchmark) Start(parent context.Context) *BenchmarkResult {
 	result := newBenchmarkResult(ctx)
 	defer cancel()
 
+	b.mu.Lock()
+	if b.seed != nil {
+		result.Seed = *b.seed
+	} else {
+		result.Seed = time.Now().UnixNano()
+	}
+	b.mu.Unlock()
+	ctx = random.WithSeed(ctx, result.Seed)
+
+	hooks := b.copyHooks()
 	step := &BenchmarkStep{
 		mu:     sync.RWMutex{},
 		result: result,
 		cancel: cancel,
+		hooks:  hooks,
+		policy: b.newErrorPolicyState(),
+
+		panicRecover: b.panicRecover,
 	}
 
 	for _, hook := range b.errorHooks {
@@ -75,128 +98,214 @@ func (b *Benchmark) Start(parent context.Context) *BenchmarkResult {
 		}(hook)
 	}
 
-	var (
-		loadParallel *parallel.Parallel
-		loadCtx      context.Context
-		loadCancel   context.CancelFunc
-	)
+	b.reportProgress(ctx, step)
 
-	step.setErrorCode(ErrPrepare)
-	for _, prepare := range b.prepareSteps {
-		var (
-			prepareCtx    context.Context
-			prepareCancel context.CancelFunc
-		)
+	phases, err := b.orderedPhases()
+	if err != nil {
+		step.AddError(failure.NewError(ErrPhase, err))
+	} else {
+		for _, phase := range phases {
+			record := newBenchmarkPhaseResult(phase.Name)
+			result.addPhaseResult(record)
+			step.setPhase(phase.Name)
+			for _, hook := range hooks.phaseStart {
+				hook(record, step)
+			}
 
-		if b.prepareTimeout > 0 {
-			prepareCtx, prepareCancel = context.WithTimeout(ctx, b.prepareTimeout)
-		} else {
-			prepareCtx, prepareCancel = context.WithCancel(ctx)
-		}
-		defer prepareCancel()
+			completed := b.runPhase(ctx, step, phase)
+			record.Duration = time.Since(record.StartedAt)
+			if completed {
+				record.Status = PhaseCompleted
+			} else {
+				record.Status = PhaseAborted
+			}
 
-		if err := panicWrapper(b.panicRecover, func() error { return prepare(prepareCtx, step) }); err != nil {
-			for _, ignore := range b.ignoreCodes {
-				if failure.IsCode(err, ignore) {
-					goto Result
-				}
+			for _, hook := range hooks.phaseEnd {
+				hook(record, step)
+			}
+			if !completed {
+				break
 			}
-			step.AddError(err)
-			goto Result
 		}
 	}
 
-	result.Errors.Wait()
+	cancel()
+	step.wait()
+	step.setErrorCode(nil)
+	step.setPhase("")
+	result.FinishedAt = time.Now()
+
+	if b.criteria != nil {
+		result.Verdict = b.criteria.Evaluate(result)
+	}
 
-	if ctx.Err() != nil {
-		goto Result
+	for _, hook := range hooks.finish {
+		hook(result, step)
 	}
 
-	step.setErrorCode(ErrLoad)
-	if b.loadTimeout > 0 {
-		loadCtx, loadCancel = context.WithTimeout(ctx, b.loadTimeout)
+	return result
+}
+
+func (b *Benchmark) runPhase(ctx context.Context, step *BenchmarkStep, phase *BenchmarkPhase) bool {
+	b.mu.Lock()
+	entries := make([]*benchmarkStepEntry, len(phase.steps))
+	copy(entries, phase.steps)
+	b.mu.Unlock()
+
+	var (
+		phaseCtx    context.Context
+		phaseCancel context.CancelFunc
+	)
+	if phase.Timeout > 0 {
+		phaseCtx, phaseCancel = context.WithTimeout(ctx, phase.Timeout)
 	} else {
-		loadCtx, loadCancel = context.WithCancel(ctx)
-	}
-	loadParallel = parallel.NewParallel(loadCtx, -1)
-
-	for _, load := range b.loadSteps {
-		func(f BenchmarkStepFunc) {
-			loadParallel.Do(func(c context.Context) {
-				if err := panicWrapper(b.panicRecover, func() error { return f(c, step) }); err != nil {
-					for _, ignore := range b.ignoreCodes {
-						if failure.IsCode(err, ignore) {
-							return
-						}
-					}
-					step.AddError(err)
-				}
-			})
-		}(load)
+		phaseCtx, phaseCancel = context.WithCancel(ctx)
 	}
-	loadParallel.Wait()
-	loadCancel()
+	defer phaseCancel()
+
+	softDeadline := make(chan struct{})
+	go func() {
+		var timeout <-chan time.Time
+		if phase.SoftTimeout > 0 {
+			timer := time.NewTimer(phase.SoftTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
 
-	result.Errors.Wait()
+		select {
+		case <-timeout:
+		case <-phaseCtx.Done():
+		}
+		close(softDeadline)
+	}()
 
-	if ctx.Err() != nil {
-		goto Result
+	step.setErrorCode(phase.ErrorCode)
+	step.setSoftDeadline(softDeadline)
+	if phase.Warmup > 0 {
+		step.setWarmup(time.Now().Add(phase.Warmup))
+	} else {
+		step.setWarmup(time.Time{})
 	}
 
-	step.setErrorCode(ErrValidation)
-	for _, validation := range b.validationSteps {
-		if err := panicWrapper(b.panicRecover, func() error { return validation(ctx, step) }); err != nil {
-			for _, ignore := range b.ignoreCodes {
-				if failure.IsCode(err, ignore) {
-					goto Result
+	switch phase.Mode {
+	case PhaseParallel:
+		if len(entries) > 0 {
+			phaseParallel := parallel.NewParallel(phaseCtx, -1)
+			for _, entry := range entries {
+				func(entry *benchmarkStepEntry) {
+					phaseParallel.Do(func(c context.Context) {
+						b.execStep(c, step, phase, entry)
+					})
+				}(entry)
+			}
+			phaseParallel.Wait()
+		}
+		phaseCancel()
+	default:
+		for idx, entry := range entries {
+			if err := b.execStep(phaseCtx, step, phase, entry); err != nil {
+				for _, skipped := range entries[idx+1:] {
+					step.result.addStepResult(newBenchmarkStepResult(phase.Name, skipped.name, StepSkipped))
 				}
+				return false
 			}
-			step.AddError(err)
-			goto Result
 		}
 	}
 
-Result:
-	cancel()
-	step.wait()
-	step.setErrorCode(nil)
+	step.result.Errors.Wait()
 
-	return result
+	return ctx.Err() == nil
 }
 
-func (b *Benchmark) OnError(f BenchmarkErrorHook) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+func (b *Benchmark) execStep(ctx context.Context, step *BenchmarkStep, phase *BenchmarkPhase, entry *benchmarkStepEntry) error {
+	record := newBenchmarkStepResult(phase.Name, entry.name, StepRunning)
+	record.StartedAt = time.Now()
+	step.result.addStepResult(record)
+
+	child := step.child(record)
+	ctx = random.WithDerivedSeed(ctx, phase.Name+"/"+entry.name)
+
+	var err error
+	attempts := 0
+	for {
+		attempts++
+		step.result.updateStepResult(record, func(r *BenchmarkStepResult) {
+			r.Attempts = attempts
+		})
+
+		err = b.execStepOnce(ctx, child, phase, entry)
+		if err == nil || attempts >= entry.attempts || ctx.Err() != nil || b.isIgnored(err) {
+			break
+		}
 
-	b.errorHooks = append(b.errorHooks, f)
-}
+		select {
+		case <-ctx.Done():
+		case <-time.After(entry.retryInterval):
+		}
+	}
 
-func (b *Benchmark) Prepare(f BenchmarkStepFunc) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	status := StepSucceeded
+	if err != nil {
+		switch {
+		case failure.Is(err, context.DeadlineExceeded):
+			status = StepTimedOut
+		case failure.Is(err, context.Canceled):
+			status = StepCanceled
+		default:
+			status = StepFailed
+		}
+	}
+	step.result.updateStepResult(record, func(r *BenchmarkStepResult) {
+		r.Duration = time.Since(r.StartedAt)
+		r.Status = status
+	})
+
+	if err != nil {
+		if !b.isIgnored(err) {
+			child.addError(err, true)
+		}
+	}
 
-	b.prepareSteps = append(b.prepareSteps, f)
+	return err
 }
 
-func (b *Benchmark) Load(f BenchmarkStepFunc) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+func (b *Benchmark) execStepOnce(ctx context.Context, step *BenchmarkStep, phase *BenchmarkPhase, entry *benchmarkStepEntry) error {
+	timeout := entry.timeout
+	if timeout <= 0 {
+		timeout = phase.StepTimeout
+	}
 
-	b.loadSteps = append(b.loadSteps, f)
+	var (
+		stepCtx    context.Context
+		stepCancel context.CancelFunc
+	)
+	if timeout > 0 {
+		stepCtx, stepCancel = context.WithTimeout(ctx, timeout)
+	} else {
+		stepCtx, stepCancel = context.WithCancel(ctx)
+	}
+	defer stepCancel()
+
+	return panicWrapper(b.panicRecover, func() error { return entry.f(stepCtx, step) })
 }
 
-func (b *Benchmark) Validation(f BenchmarkStepFunc) {
+func (b *Benchmark) OnError(f BenchmarkErrorHook) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	b.validationSteps = append(b.validationSteps, f)
+	b.errorHooks = append(b.errorHooks, f)
 }
 
-func (b *Benchmark) IgnoreErrorCode(code failure.Code) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+func (b *Benchmark) Prepare(f BenchmarkStepFunc, opts ...BenchmarkStepOption) {
+	b.Phase(PhasePrepare, f, opts...)
+}
+
+func (b *Benchmark) Load(f BenchmarkStepFunc, opts ...BenchmarkStepOption) {
+	b.Phase(PhaseLoad, f, opts...)
+}
 
-	b.ignoreCodes = append(b.ignoreCodes, code)
+func (b *Benchmark) Validation(f BenchmarkStepFunc, opts ...BenchmarkStepOption) {
+	b.Phase(PhaseValidation, f, opts...)
 }
 
 func panicWrapper(on bool, f func() error) (err error) {
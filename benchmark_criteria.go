@@ -0,0 +1,129 @@
+package isucandar
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/isucon/isucandar/failure"
+	"github.com/isucon/isucandar/score"
+)
+
+type Verdict struct {
+	Passed    bool     `json:"passed"`
+	Score     int64    `json:"score"`
+	RawScore  int64    `json:"raw_score"`
+	Deduction int64    `json:"deduction"`
+	Reasons   []string `json:"reasons"`
+}
+
+func (v *Verdict) Fail(format string, args ...interface{}) {
+	v.Passed = false
+	v.Reasons = append(v.Reasons, fmt.Sprintf(format, args...))
+}
+
+func (v *Verdict) Deduct(points int64, format string, args ...interface{}) {
+	if points <= 0 {
+		return
+	}
+	v.Deduction += points
+	v.Reasons = append(v.Reasons, fmt.Sprintf(format, args...))
+}
+
+type CriteriaRule func(*BenchmarkResult, *Verdict)
+
+type Criteria struct {
+	mu    sync.RWMutex
+	rules []CriteriaRule
+}
+
+func NewCriteria(rules ...CriteriaRule) *Criteria {
+	return &Criteria{
+		mu:    sync.RWMutex{},
+		rules: rules,
+	}
+}
+
+func (c *Criteria) Add(rule CriteriaRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rules = append(c.rules, rule)
+}
+
+// ルールは登録順に評価され、減点は最後に素点から差し引かれます。
+// 不合格の場合の最終スコアは 0 になります。
+func (c *Criteria) Evaluate(result *BenchmarkResult) *Verdict {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	verdict := &Verdict{
+		Passed:    true,
+		RawScore:  result.Score.Sum(),
+		Deduction: 0,
+		Reasons:   []string{},
+	}
+
+	if reason := result.AbortReason(); reason != "" {
+		verdict.Fail("benchmark aborted: %s", reason)
+	} else if !result.Completed() {
+		verdict.Fail("benchmark aborted")
+	}
+
+	for _, rule := range c.rules {
+		rule(result, verdict)
+	}
+
+	verdict.Score = verdict.RawScore - verdict.Deduction
+	if verdict.Score < 0 || !verdict.Passed {
+		verdict.Score = 0
+	}
+
+	return verdict
+}
+
+func FailIfErrorCountExceeds(code failure.Code, max int64) CriteriaRule {
+	return func(result *BenchmarkResult, verdict *Verdict) {
+		if count := result.Errors.Count()[code.ErrorCode()]; count > max {
+			verdict.Fail("%s errors: %d (max %d)", code.ErrorCode(), count, max)
+		}
+	}
+}
+
+func RequireScoreTag(tag score.ScoreTag, min int64) CriteriaRule {
+	return func(result *BenchmarkResult, verdict *Verdict) {
+		if count := result.Score.Breakdown()[tag]; count < min {
+			verdict.Fail("%s: %d (min %d)", tag, count, min)
+		}
+	}
+}
+
+// numerator / denominator が min を下回ると不合格にします。denominator が 0 の時は評価しません。
+func RequireScoreRatio(numerator, denominator score.ScoreTag, min float64) CriteriaRule {
+	return func(result *BenchmarkResult, verdict *Verdict) {
+		breakdown := result.Score.Breakdown()
+		if breakdown[denominator] == 0 {
+			return
+		}
+
+		ratio := float64(breakdown[numerator]) / float64(breakdown[denominator])
+		if ratio < min {
+			verdict.Fail("%s/%s ratio: %.3f (min %.3f)", numerator, denominator, ratio, min)
+		}
+	}
+}
+
+func DeductPerError(code failure.Code, points int64) CriteriaRule {
+	return func(result *BenchmarkResult, verdict *Verdict) {
+		count := result.Errors.Count()[code.ErrorCode()]
+		verdict.Deduct(count*points, "%s errors: %d (-%d points)", code.ErrorCode(), count, count*points)
+	}
+}
+
+// 素点に対して rate (0.01 で 1%) をエラー1件ごとに減点します
+func DeductRatePerError(code failure.Code, rate float64) CriteriaRule {
+	return func(result *BenchmarkResult, verdict *Verdict) {
+		count := result.Errors.Count()[code.ErrorCode()]
+		points := int64(float64(verdict.RawScore) * rate * float64(count))
+		verdict.Deduct(points, "%s errors: %d (-%d points)", code.ErrorCode(), count, points)
+	}
+}
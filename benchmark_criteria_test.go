@@ -0,0 +1,109 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+var (
+	ErrCritical failure.StringCode = "critical"
+	ErrTimeout  failure.StringCode = "request-timeout"
+)
+
+func newCriteriaBenchmark(t *testing.T, load BenchmarkStepFunc, opts ...BenchmarkOption) *BenchmarkResult {
+	b, err := NewBenchmark(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Prepare(func(_ context.Context, s *BenchmarkStep) error {
+		s.Result().Score.Set("get", 10)
+		s.Result().Score.Set("post", 100)
+		return nil
+	})
+	b.Load(load)
+
+	return b.Start(context.Background())
+}
+
+func TestCriteriaPassed(t *testing.T) {
+	criteria := NewCriteria(
+		FailIfErrorCountExceeds(ErrCritical, 0),
+		RequireScoreTag("post", 1),
+		RequireScoreRatio("post", "get", 0.5),
+	)
+
+	result := newCriteriaBenchmark(t, func(_ context.Context, s *BenchmarkStep) error {
+		s.AddScore("get")
+		s.AddScore("post")
+		return nil
+	}, WithCriteria(criteria))
+
+	verdict := result.Verdict
+	if verdict == nil || !verdict.Passed || verdict.Score != 110 || len(verdict.Reasons) != 0 {
+		t.Fatalf("%+v", verdict)
+	}
+
+	if !result.Snapshot().Passed || result.Snapshot().Verdict != verdict {
+		t.Fatalf("%+v", result.Snapshot())
+	}
+}
+
+func TestCriteriaFailed(t *testing.T) {
+	criteria := NewCriteria(
+		FailIfErrorCountExceeds(ErrCritical, 0),
+		RequireScoreTag("post", 1),
+		RequireScoreRatio("post", "get", 0.5),
+	)
+
+	result := newCriteriaBenchmark(t, func(_ context.Context, s *BenchmarkStep) error {
+		s.AddScore("get")
+		s.AddScore("get")
+		s.AddError(failure.NewError(ErrCritical, errors.New("broken")))
+		return nil
+	})
+
+	verdict := criteria.Evaluate(result)
+	if verdict.Passed || verdict.Score != 0 || verdict.RawScore != 20 || len(verdict.Reasons) != 3 {
+		t.Fatalf("%+v", verdict)
+	}
+}
+
+func TestCriteriaDeduction(t *testing.T) {
+	criteria := NewCriteria()
+	criteria.Add(DeductPerError(ErrTimeout, 5))
+	criteria.Add(DeductRatePerError(ErrTimeout, 0.01))
+
+	result := newCriteriaBenchmark(t, func(_ context.Context, s *BenchmarkStep) error {
+		for i := 0; i < 10; i++ {
+			s.AddScore("post")
+		}
+		s.AddError(failure.NewError(ErrTimeout, errors.New("timeout")))
+		s.AddError(failure.NewError(ErrTimeout, errors.New("timeout")))
+		return nil
+	})
+
+	verdict := criteria.Evaluate(result)
+	// 1000 - 2 * 5 - 1000 * 0.01 * 2
+	if !verdict.Passed || verdict.Deduction != 30 || verdict.Score != 970 || len(verdict.Reasons) != 2 {
+		t.Fatalf("%+v", verdict)
+	}
+}
+
+func TestCriteriaAborted(t *testing.T) {
+	criteria := NewCriteria()
+
+	result := newCriteriaBenchmark(t, func(_ context.Context, s *BenchmarkStep) error {
+		s.AddScore("post")
+		s.Cancel()
+		return nil
+	})
+
+	verdict := criteria.Evaluate(result)
+	if verdict.Passed || verdict.Score != 0 || len(verdict.Reasons) != 1 {
+		t.Fatalf("%+v", verdict)
+	}
+}
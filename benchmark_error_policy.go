@@ -0,0 +1,129 @@
+package isucandar
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+type ErrorPolicy struct {
+	// エラーを記録せずに捨てます
+	Ignore bool
+	// 1件でも発生した時点でベンチマークを中断します
+	Critical bool
+	// 発生件数が Limit に達した時点でベンチマークを中断します
+	Limit int64
+	// 直近1秒間の発生件数が RatePerSecond に達した時点でベンチマークを中断します
+	RatePerSecond int64
+}
+
+func IgnoreError() ErrorPolicy {
+	return ErrorPolicy{Ignore: true}
+}
+
+func CriticalError() ErrorPolicy {
+	return ErrorPolicy{Critical: true}
+}
+
+func LimitedError(limit int64) ErrorPolicy {
+	return ErrorPolicy{Limit: limit}
+}
+
+func RateLimitedError(perSecond int64) ErrorPolicy {
+	return ErrorPolicy{RatePerSecond: perSecond}
+}
+
+func (b *Benchmark) SetErrorPolicy(code failure.Code, policy ErrorPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.errorPolicies[code.ErrorCode()] = policy
+}
+
+func (b *Benchmark) IgnoreErrorCode(code failure.Code) {
+	b.SetErrorPolicy(code, IgnoreError())
+}
+
+func (b *Benchmark) isIgnored(err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, code := range failure.GetErrorCodes(err) {
+		if policy, found := b.errorPolicies[code]; found && policy.Ignore {
+			return true
+		}
+	}
+	return false
+}
+
+type errorPolicyState struct {
+	mu       sync.Mutex
+	policies map[string]ErrorPolicy
+	counts   map[string]int64
+	recent   map[string][]time.Time
+}
+
+func (b *Benchmark) newErrorPolicyState() *errorPolicyState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	policies := make(map[string]ErrorPolicy, len(b.errorPolicies))
+	for code, policy := range b.errorPolicies {
+		policies[code] = policy
+	}
+
+	return &errorPolicyState{
+		mu:       sync.Mutex{},
+		policies: policies,
+		counts:   make(map[string]int64),
+		recent:   make(map[string][]time.Time),
+	}
+}
+
+// エラーを記録すべきかどうかと、中断すべき場合はその理由を返します
+func (s *errorPolicyState) check(err error) (bool, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := failure.GetErrorCodes(err)
+	for _, code := range codes {
+		if policy, found := s.policies[code]; found && policy.Ignore {
+			return false, ""
+		}
+	}
+
+	reason := ""
+	now := time.Now()
+	for _, code := range codes {
+		policy, found := s.policies[code]
+		if !found {
+			continue
+		}
+
+		s.counts[code]++
+
+		if policy.Critical && reason == "" {
+			reason = fmt.Sprintf("critical error: %s", code)
+		}
+
+		if policy.Limit > 0 && s.counts[code] >= policy.Limit && reason == "" {
+			reason = fmt.Sprintf("too many errors: %s (%d)", code, s.counts[code])
+		}
+
+		if policy.RatePerSecond > 0 {
+			recent := append(s.recent[code], now)
+			for len(recent) > 0 && now.Sub(recent[0]) >= time.Second {
+				recent = recent[1:]
+			}
+			s.recent[code] = recent
+
+			if int64(len(recent)) >= policy.RatePerSecond && reason == "" {
+				reason = fmt.Sprintf("error rate exceeded: %s (%d/s)", code, len(recent))
+			}
+		}
+	}
+
+	return true, reason
+}
@@ -0,0 +1,117 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+var (
+	ErrPolicyCritical failure.StringCode = "policy-critical"
+	ErrPolicyLimited  failure.StringCode = "policy-limited"
+	ErrPolicyRate     failure.StringCode = "policy-rate"
+)
+
+func TestBenchmarkCriticalErrorPolicy(t *testing.T) {
+	b := newBenchmark(WithErrorPolicy(ErrPolicyCritical, CriticalError()))
+
+	b.Load(func(ctx context.Context, s *BenchmarkStep) error {
+		s.AddError(failure.NewError(ErrPolicyCritical, errors.New("critical")))
+		<-ctx.Done()
+		return nil
+	})
+
+	validated := false
+	b.Validation(func(_ context.Context, _ *BenchmarkStep) error {
+		validated = true
+		return nil
+	})
+
+	result := b.Start(context.Background())
+
+	if validated {
+		t.Fatal("benchmark not aborted")
+	}
+
+	if result.AbortReason() != "critical error: policy-critical" {
+		t.Fatal(result.AbortReason())
+	}
+
+	if result.Errors.Count()[string(ErrPolicyCritical)] != 1 {
+		t.Fatal(result.Errors.All())
+	}
+}
+
+func TestBenchmarkLimitedErrorPolicy(t *testing.T) {
+	b := newBenchmark()
+	b.SetErrorPolicy(ErrPolicyLimited, LimitedError(3))
+
+	count := 0
+	b.Load(func(ctx context.Context, s *BenchmarkStep) error {
+		for ctx.Err() == nil && count < 10 {
+			count++
+			s.AddError(failure.NewError(ErrPolicyLimited, errors.New("limited")))
+		}
+		return nil
+	})
+
+	result := b.Start(context.Background())
+
+	if count != 3 {
+		t.Fatal(count)
+	}
+
+	if result.AbortReason() == "" || result.Completed() {
+		t.Fatal(result.AbortReason())
+	}
+}
+
+func TestBenchmarkRateLimitedErrorPolicy(t *testing.T) {
+	b := newBenchmark(WithErrorPolicy(ErrPolicyRate, RateLimitedError(3)))
+
+	b.Load(func(ctx context.Context, s *BenchmarkStep) error {
+		s.AddError(failure.NewError(ErrPolicyRate, errors.New("rate")))
+		s.AddError(failure.NewError(ErrPolicyRate, errors.New("rate")))
+		<-time.After(1 * time.Second)
+		s.AddError(failure.NewError(ErrPolicyRate, errors.New("rate")))
+		return nil
+	})
+
+	result := b.Start(context.Background())
+
+	if result.AbortReason() != "" || !result.Completed() {
+		t.Fatal(result.AbortReason())
+	}
+
+	b = newBenchmark(WithErrorPolicy(ErrPolicyRate, RateLimitedError(3)))
+	b.Load(func(ctx context.Context, s *BenchmarkStep) error {
+		for i := 0; i < 3; i++ {
+			s.AddError(failure.NewError(ErrPolicyRate, errors.New("rate")))
+		}
+		return nil
+	})
+
+	result = b.Start(context.Background())
+
+	if result.AbortReason() == "" {
+		t.Fatal("not aborted")
+	}
+}
+
+func TestBenchmarkIgnoreErrorPolicy(t *testing.T) {
+	b := newBenchmark(WithErrorPolicy(ErrIgnore, IgnoreError()))
+
+	b.Load(func(ctx context.Context, s *BenchmarkStep) error {
+		s.AddError(failure.NewError(ErrIgnore, errors.New("ignore")))
+		return nil
+	})
+
+	result := b.Start(context.Background())
+
+	if len(result.Errors.All()) != 0 {
+		t.Fatal(result.Errors.All())
+	}
+}
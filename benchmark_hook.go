@@ -0,0 +1,58 @@
+package isucandar
+
+import (
+	"github.com/isucon/isucandar/score"
+)
+
+type BenchmarkPhaseHook func(*BenchmarkPhaseResult, *BenchmarkStep)
+type BenchmarkScoreHook func(score.ScoreTag, *BenchmarkStep)
+type BenchmarkFinishHook func(*BenchmarkResult, *BenchmarkStep)
+
+type benchmarkHooks struct {
+	phaseStart []BenchmarkPhaseHook
+	phaseEnd   []BenchmarkPhaseHook
+	score      []BenchmarkScoreHook
+	finish     []BenchmarkFinishHook
+}
+
+func (b *Benchmark) OnPhaseStart(f BenchmarkPhaseHook) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.hooks.phaseStart = append(b.hooks.phaseStart, f)
+}
+
+func (b *Benchmark) OnPhaseEnd(f BenchmarkPhaseHook) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.hooks.phaseEnd = append(b.hooks.phaseEnd, f)
+}
+
+// スコアが追加されるたびに、追加したステップの goroutine 上で同期的に呼び出されます
+func (b *Benchmark) OnScore(f BenchmarkScoreHook) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.hooks.score = append(b.hooks.score, f)
+}
+
+// すべてのスコアとエラーの集計が完了した後に呼び出されます
+func (b *Benchmark) OnFinish(f BenchmarkFinishHook) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.hooks.finish = append(b.hooks.finish, f)
+}
+
+func (b *Benchmark) copyHooks() *benchmarkHooks {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return &benchmarkHooks{
+		phaseStart: append([]BenchmarkPhaseHook{}, b.hooks.phaseStart...),
+		phaseEnd:   append([]BenchmarkPhaseHook{}, b.hooks.phaseEnd...),
+		score:      append([]BenchmarkScoreHook{}, b.hooks.score...),
+		finish:     append([]BenchmarkFinishHook{}, b.hooks.finish...),
+	}
+}
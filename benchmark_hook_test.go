@@ -0,0 +1,88 @@
+package isucandar
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/isucon/isucandar/score"
+)
+
+func TestBenchmarkLifecycleHooks(t *testing.T) {
+	b := newBenchmark()
+
+	mu := sync.Mutex{}
+	events := []string{}
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	b.OnPhaseStart(func(p *BenchmarkPhaseResult, s *BenchmarkStep) {
+		if p.Status != PhaseRunning || s.Phase() != p.Name {
+			t.Fatalf("%+v", p)
+		}
+		record("start:" + p.Name)
+	})
+	b.OnPhaseEnd(func(p *BenchmarkPhaseResult, _ *BenchmarkStep) {
+		record("end:" + p.Name + ":" + string(p.Status))
+	})
+	b.OnScore(func(tag score.ScoreTag, s *BenchmarkStep) {
+		record("score:" + string(tag) + ":" + s.Phase())
+	})
+
+	var finished *BenchmarkResult
+	b.OnFinish(func(r *BenchmarkResult, _ *BenchmarkStep) {
+		finished = r
+		record("finish")
+	})
+
+	b.Load(func(_ context.Context, s *BenchmarkStep) error {
+		s.AddScore("load")
+		return nil
+	})
+
+	result := b.Start(context.Background())
+
+	if finished != result {
+		t.Fatal("finish hook not called with result")
+	}
+
+	expected := []string{
+		"start:prepare", "end:prepare:completed",
+		"start:load", "score:load:load", "end:load:completed",
+		"start:validation", "end:validation:completed",
+		"finish",
+	}
+	if len(events) != len(expected) {
+		t.Fatal(events)
+	}
+	for i, event := range expected {
+		if events[i] != event {
+			t.Fatal(events)
+		}
+	}
+}
+
+func TestBenchmarkPhaseEndHookAbort(t *testing.T) {
+	b := newBenchmark()
+
+	aborted := ""
+	b.OnPhaseEnd(func(p *BenchmarkPhaseResult, s *BenchmarkStep) {
+		if p.Status == PhaseAborted {
+			aborted = p.Name
+		}
+	})
+	b.OnPhaseStart(func(p *BenchmarkPhaseResult, s *BenchmarkStep) {
+		if p.Name == PhaseLoad {
+			s.Cancel()
+		}
+	})
+
+	b.Start(context.Background())
+
+	if aborted != PhaseLoad {
+		t.Fatal(aborted)
+	}
+}
@@ -0,0 +1,180 @@
+package isucandar
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/isucon/isucandar/random"
+	"github.com/isucon/isucandar/worker"
+)
+
+type loadMixEntry struct {
+	name   string
+	weight int
+	f      BenchmarkStepFunc
+}
+
+// LoadMix は重みに従ってシナリオを選びながら Worker で繰り返し実行する負荷走行用のドライバです。
+// シナリオごとの実行回数、スコア、エラー数は BenchmarkResult.Scenarios() に集計されます。
+type LoadMix struct {
+	mu      sync.RWMutex
+	entries []*loadMixEntry
+	worker  *worker.Worker
+	step    *BenchmarkStep
+	running int
+	idle    *sync.Cond
+}
+
+func NewLoadMix(opts ...worker.WorkerOption) (*LoadMix, error) {
+	mix := &LoadMix{
+		mu:      sync.RWMutex{},
+		entries: []*loadMixEntry{},
+		running: 0,
+	}
+	mix.idle = sync.NewCond(&mix.mu)
+
+	defaults := []worker.WorkerOption{worker.WithInfinityLoop(), worker.WithMaxParallelism(1)}
+	w, err := worker.NewWorker(mix.iterate, append(defaults, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	mix.worker = w
+
+	return mix, nil
+}
+
+func (m *LoadMix) Add(name string, weight int, f BenchmarkStepFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = append(m.entries, &loadMixEntry{
+		name:   name,
+		weight: weight,
+		f:      f,
+	})
+}
+
+// AddScenarios は有効なシナリオの Load を ScenarioEntry の重みで追加します。
+// 有効な複合シナリオに含まれるシナリオは、その複合シナリオの中でのみ実行されます。
+func (m *LoadMix) AddScenarios(r *ScenarioRegistry) {
+	for _, entry := range r.topLevel() {
+		if entry.Scenario.Load != nil {
+			m.Add(entry.Name, entry.Weight(), entry.Scenario.Load)
+		}
+	}
+}
+
+// SetWeight は実行中であっても次のイテレーションから反映されます
+func (m *LoadMix) SetWeight(name string, weight int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, entry := range m.entries {
+		if entry.name == name {
+			entry.weight = weight
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrScenarioNotFound, name)
+}
+
+func (m *LoadMix) Weights() map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	weights := make(map[string]int, len(m.entries))
+	for _, entry := range m.entries {
+		weights[entry.name] = entry.weight
+	}
+	return weights
+}
+
+func (m *LoadMix) Worker() *worker.Worker {
+	return m.worker
+}
+
+func (m *LoadMix) Load(ctx context.Context, step *BenchmarkStep) error {
+	m.mu.Lock()
+	m.step = step
+	m.mu.Unlock()
+
+	m.worker.Process(ctx)
+
+	// Process はキャンセル時に実行中のイテレーションを待たずに返るため、ここで待ちます。
+	// 以降に開始されたイテレーションは step を参照せずに終了します。
+	m.mu.Lock()
+	m.step = nil
+	for m.running > 0 {
+		m.idle.Wait()
+	}
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *LoadMix) choose(ctx context.Context) *loadMixEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	total := 0
+	for _, entry := range m.entries {
+		if entry.weight > 0 {
+			total += entry.weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	n := random.FromContext(ctx).Intn(total)
+	for _, entry := range m.entries {
+		if entry.weight <= 0 {
+			continue
+		}
+		if n < entry.weight {
+			return entry
+		}
+		n -= entry.weight
+	}
+
+	return nil
+}
+
+func (m *LoadMix) iterate(ctx context.Context, _ int) {
+	m.mu.Lock()
+	step := m.step
+	if step == nil {
+		m.mu.Unlock()
+		return
+	}
+	m.running++
+	m.mu.Unlock()
+	defer m.done()
+
+	entry := m.choose(ctx)
+	if entry == nil {
+		<-ctx.Done()
+		return
+	}
+
+	scenario := step.result.scenarioResult(entry.name)
+	atomic.AddInt64(&scenario.Iterations, 1)
+
+	child := step.scenarioChild(scenario)
+	if err := panicWrapper(child.panicRecover, func() error { return entry.f(ctx, child) }); err != nil {
+		child.AddError(err)
+	}
+}
+
+func (m *LoadMix) done() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.running--
+	if m.running == 0 {
+		m.idle.Broadcast()
+	}
+}
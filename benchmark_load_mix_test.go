@@ -0,0 +1,148 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+	"github.com/isucon/isucandar/score"
+	"github.com/isucon/isucandar/worker"
+)
+
+func TestLoadMix(t *testing.T) {
+	mix, err := NewLoadMix(worker.WithMaxParallelism(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagged := func(tag score.ScoreTag, fail bool) BenchmarkStepFunc {
+		return func(_ context.Context, s *BenchmarkStep) error {
+			s.AddScore(tag)
+			time.Sleep(100 * time.Microsecond)
+			if fail {
+				return errors.New("purchase failed")
+			}
+			return nil
+		}
+	}
+
+	mix.Add("browse", 6, tagged("browse", false))
+	mix.Add("search", 3, tagged("search", false))
+	mix.Add("purchase", 1, tagged("purchase", true))
+
+	b := newBenchmark(WithLoadTimeout(100*time.Millisecond), WithSeed(1))
+	b.Load(mix.Load)
+
+	result := b.Start(context.Background())
+
+	scenarios := result.Scenarios()
+	browse, search, purchase := scenarios["browse"], scenarios["search"], scenarios["purchase"]
+	if browse == nil || search == nil || purchase == nil {
+		t.Fatal(scenarios)
+	}
+
+	browseIterations := atomic.LoadInt64(&browse.Iterations)
+	searchIterations := atomic.LoadInt64(&search.Iterations)
+	purchaseIterations := atomic.LoadInt64(&purchase.Iterations)
+	if !(browseIterations > searchIterations && searchIterations > purchaseIterations && purchaseIterations > 0) {
+		t.Fatal(browseIterations, searchIterations, purchaseIterations)
+	}
+
+	if atomic.LoadInt64(&browse.ErrorCount) != 0 || atomic.LoadInt64(&purchase.ErrorCount) == 0 {
+		t.Fatal(browse.ErrorCount, purchase.ErrorCount)
+	}
+
+	if browse.Breakdown()["browse"] == 0 || browse.Breakdown()["search"] != 0 {
+		t.Fatal(browse.Breakdown())
+	}
+
+	snapshot := result.Snapshot()
+	if len(snapshot.Scenarios) != 3 || snapshot.Scenarios[0].Name != "browse" {
+		t.Fatalf("%+v", snapshot.Scenarios)
+	}
+}
+
+func TestLoadMixSetWeight(t *testing.T) {
+	mix, err := NewLoadMix()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewScenarioRegistry()
+	registry.Register("browse", (&loadOnlyScenario{}).scenario(), WithScenarioWeight(2))
+	registry.Register("search", (&loadOnlyScenario{}).scenario(), WithScenarioDisabled())
+	mix.AddScenarios(registry)
+
+	if weights := mix.Weights(); len(weights) != 1 || weights["browse"] != 2 {
+		t.Fatal(weights)
+	}
+
+	if err := mix.SetWeight("unknown", 1); !errors.Is(err, ErrScenarioNotFound) {
+		t.Fatal(err)
+	}
+
+	if err := mix.SetWeight("browse", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry := mix.choose(context.Background()); entry != nil {
+		t.Fatal(entry)
+	}
+}
+
+func TestLoadMixWaitsRunningIterations(t *testing.T) {
+	mix, err := NewLoadMix(worker.WithMaxParallelism(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := int32(0)
+	mix.Add("slow", 1, func(ctx context.Context, _ *BenchmarkStep) error {
+		atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+
+		<-ctx.Done()
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+
+	after := make(chan int32, 1)
+	b := newBenchmark(WithLoadTimeout(10 * time.Millisecond))
+	b.Load(func(ctx context.Context, s *BenchmarkStep) error {
+		err := mix.Load(ctx, s)
+		after <- atomic.LoadInt32(&active)
+		return err
+	})
+
+	for i := 0; i < 3; i++ {
+		b.Start(context.Background())
+
+		if n := <-after; n != 0 {
+			t.Fatalf("iterations still running after Load: %d", n)
+		}
+	}
+}
+
+func TestLoadMixPanic(t *testing.T) {
+	mix, err := NewLoadMix(worker.WithLoopCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mix.Add("broken", 1, func(_ context.Context, _ *BenchmarkStep) error {
+		panic("load mix panic")
+	})
+
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Load(mix.Load)
+	result := b.Start(context.Background())
+
+	if errs := result.Errors.All(); len(errs) != 1 || !failure.IsCode(errs[0], ErrPanic) {
+		t.Fatal(errs)
+	}
+}
@@ -2,24 +2,83 @@ package isucandar
 
 import (
 	"time"
+
+	"github.com/isucon/isucandar/failure"
 )
 
 type BenchmarkOption func(*Benchmark) error
 
+// Prepare の各ステップにそれぞれ適用されるタイムアウトです
 func WithPrepareTimeout(d time.Duration) BenchmarkOption {
+	return func(b *Benchmark) error {
+		b.setPhaseStepTimeout(PhasePrepare, d)
+		return nil
+	}
+}
+
+func WithLoadTimeout(d time.Duration) BenchmarkOption {
+	return func(b *Benchmark) error {
+		b.setPhaseTimeout(PhaseLoad, d)
+		return nil
+	}
+}
+
+func WithLoadWarmup(d time.Duration) BenchmarkOption {
+	return func(b *Benchmark) error {
+		b.setPhaseWarmup(PhaseLoad, d)
+		return nil
+	}
+}
+
+func WithValidationTimeout(d time.Duration) BenchmarkOption {
+	return func(b *Benchmark) error {
+		b.setPhaseTimeout(PhaseValidation, d)
+		return nil
+	}
+}
+
+func WithValidationSoftTimeout(d time.Duration) BenchmarkOption {
+	return func(b *Benchmark) error {
+		b.setPhaseSoftTimeout(PhaseValidation, d)
+		return nil
+	}
+}
+
+func WithCriteria(c *Criteria) BenchmarkOption {
 	return func(b *Benchmark) error {
 		b.mu.Lock()
 		defer b.mu.Unlock()
-		b.prepareTimeout = d
+		b.criteria = c
 		return nil
 	}
 }
 
-func WithLoadTimeout(d time.Duration) BenchmarkOption {
+func WithProgressInterval(d time.Duration) BenchmarkOption {
+	return func(b *Benchmark) error {
+		if d <= 0 {
+			return ErrInvalidProgressInterval
+		}
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.progressInterval = d
+		return nil
+	}
+}
+
+func WithErrorPolicy(code failure.Code, policy ErrorPolicy) BenchmarkOption {
+	return func(b *Benchmark) error {
+		b.SetErrorPolicy(code, policy)
+		return nil
+	}
+}
+
+// 同じ seed を与えると、各ステップやワーカーのイテレーションへ同じ乱数列が渡されます
+func WithSeed(seed int64) BenchmarkOption {
 	return func(b *Benchmark) error {
 		b.mu.Lock()
 		defer b.mu.Unlock()
-		b.loadTimeout = d
+		b.seed = &seed
 		return nil
 	}
 }
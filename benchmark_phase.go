@@ -0,0 +1,272 @@
+package isucandar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+var (
+	ErrPhase failure.StringCode = "phase"
+
+	ErrPhaseDuplicated = errors.New("Phase already registered")
+	ErrPhaseNotFound   = errors.New("Phase not found")
+	ErrPhaseCycle      = errors.New("Phase dependencies have a cycle")
+)
+
+const (
+	PhasePrepare    = "prepare"
+	PhaseLoad       = "load"
+	PhaseValidation = "validation"
+)
+
+type BenchmarkPhaseMode int
+
+const (
+	// 登録されたステップを順に実行し、エラーが発生した時点でベンチマークを中断します
+	PhaseSequential BenchmarkPhaseMode = iota
+	// 登録されたステップを並列に実行し、エラーが発生しても中断しません
+	PhaseParallel
+)
+
+type BenchmarkPhaseOption func(*BenchmarkPhase) error
+
+type BenchmarkPhase struct {
+	Name      string
+	ErrorCode failure.Code
+	Timeout   time.Duration
+	// 各ステップのタイムアウトです。WithStepTimeout が指定されたステップでは、そちらが優先されます
+	StepTimeout time.Duration
+	// SoftTimeout を過ぎると BenchmarkStep.SoftDeadline() が閉じられます
+	SoftTimeout time.Duration
+	// Warmup の間に追加されたスコアとエラーは集計から除外されます
+	Warmup time.Duration
+	Mode   BenchmarkPhaseMode
+
+	after  []string
+	before []string
+	steps  []*benchmarkStepEntry
+}
+
+func newBenchmarkPhase(name string, opts ...BenchmarkPhaseOption) (*BenchmarkPhase, error) {
+	phase := &BenchmarkPhase{
+		Name:        name,
+		ErrorCode:   failure.StringCode(name),
+		Timeout:     time.Duration(0),
+		StepTimeout: time.Duration(0),
+		SoftTimeout: time.Duration(0),
+		Warmup:      time.Duration(0),
+		Mode:        PhaseSequential,
+		after:       []string{},
+		before:      []string{},
+		steps:       []*benchmarkStepEntry{},
+	}
+
+	for _, opt := range opts {
+		if err := opt(phase); err != nil {
+			return nil, err
+		}
+	}
+
+	return phase, nil
+}
+
+func WithPhaseTimeout(d time.Duration) BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.Timeout = d
+		return nil
+	}
+}
+
+func WithPhaseStepTimeout(d time.Duration) BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.StepTimeout = d
+		return nil
+	}
+}
+
+func WithPhaseSoftTimeout(d time.Duration) BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.SoftTimeout = d
+		return nil
+	}
+}
+
+func WithPhaseWarmup(d time.Duration) BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.Warmup = d
+		return nil
+	}
+}
+
+func WithPhaseErrorCode(code failure.Code) BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.ErrorCode = code
+		return nil
+	}
+}
+
+func WithPhaseSequential() BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.Mode = PhaseSequential
+		return nil
+	}
+}
+
+func WithPhaseParallel() BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.Mode = PhaseParallel
+		return nil
+	}
+}
+
+func WithPhaseAfter(names ...string) BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.after = append(p.after, names...)
+		return nil
+	}
+}
+
+func WithPhaseBefore(names ...string) BenchmarkPhaseOption {
+	return func(p *BenchmarkPhase) error {
+		p.before = append(p.before, names...)
+		return nil
+	}
+}
+
+func (b *Benchmark) AddPhase(name string, opts ...BenchmarkPhaseOption) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.addPhase(name, opts...)
+}
+
+func (b *Benchmark) addPhase(name string, opts ...BenchmarkPhaseOption) error {
+	if _, found := b.phases[name]; found {
+		return fmt.Errorf("%w: %s", ErrPhaseDuplicated, name)
+	}
+
+	phase, err := newBenchmarkPhase(name, opts...)
+	if err != nil {
+		return err
+	}
+
+	b.phases[name] = phase
+	b.phaseNames = append(b.phaseNames, name)
+
+	return nil
+}
+
+func (b *Benchmark) Phase(name string, f BenchmarkStepFunc, opts ...BenchmarkStepOption) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	phase, found := b.phases[name]
+	if !found {
+		return fmt.Errorf("%w: %s", ErrPhaseNotFound, name)
+	}
+
+	entry, err := newBenchmarkStepEntry(fmt.Sprintf("%s-%d", name, len(phase.steps)+1), f, opts...)
+	if err != nil {
+		return err
+	}
+	phase.steps = append(phase.steps, entry)
+
+	return nil
+}
+
+func (b *Benchmark) setPhaseTimeout(name string, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if phase, found := b.phases[name]; found {
+		phase.Timeout = d
+	}
+}
+
+func (b *Benchmark) setPhaseStepTimeout(name string, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if phase, found := b.phases[name]; found {
+		phase.StepTimeout = d
+	}
+}
+
+func (b *Benchmark) setPhaseWarmup(name string, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if phase, found := b.phases[name]; found {
+		phase.Warmup = d
+	}
+}
+
+func (b *Benchmark) setPhaseSoftTimeout(name string, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if phase, found := b.phases[name]; found {
+		phase.SoftTimeout = d
+	}
+}
+
+// 依存関係を満たしつつ、なるべく登録順になるようにフェーズを並べます
+func (b *Benchmark) orderedPhases() ([]*BenchmarkPhase, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	deps := make(map[string]map[string]bool, len(b.phaseNames))
+	for _, name := range b.phaseNames {
+		deps[name] = make(map[string]bool)
+	}
+	for _, name := range b.phaseNames {
+		phase := b.phases[name]
+		for _, dep := range phase.after {
+			if _, found := b.phases[dep]; !found {
+				return nil, fmt.Errorf("%w: %s (required by %s)", ErrPhaseNotFound, dep, name)
+			}
+			deps[name][dep] = true
+		}
+		for _, next := range phase.before {
+			if _, found := b.phases[next]; !found {
+				return nil, fmt.Errorf("%w: %s (required by %s)", ErrPhaseNotFound, next, name)
+			}
+			deps[next][name] = true
+		}
+	}
+
+	done := make(map[string]bool, len(b.phaseNames))
+	ordered := make([]*BenchmarkPhase, 0, len(b.phaseNames))
+	for len(ordered) < len(b.phaseNames) {
+		progress := false
+		for _, name := range b.phaseNames {
+			if done[name] {
+				continue
+			}
+
+			ready := true
+			for dep := range deps[name] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				done[name] = true
+				ordered = append(ordered, b.phases[name])
+				progress = true
+				break
+			}
+		}
+
+		if !progress {
+			return nil, ErrPhaseCycle
+		}
+	}
+
+	return ordered, nil
+}
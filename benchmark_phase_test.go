@@ -0,0 +1,112 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+func TestBenchmarkPhaseOrder(t *testing.T) {
+	b := newBenchmark()
+
+	mu := sync.Mutex{}
+	executed := []string{}
+	record := func(name string) BenchmarkStepFunc {
+		return func(_ context.Context, _ *BenchmarkStep) error {
+			mu.Lock()
+			executed = append(executed, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	if err := b.AddPhase("final-check", WithPhaseAfter(PhaseValidation)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddPhase("warmup", WithPhaseAfter(PhasePrepare), WithPhaseBefore(PhaseLoad), WithPhaseParallel()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddPhase("initialize", WithPhaseBefore(PhasePrepare)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"final-check", "warmup", "initialize", PhasePrepare, PhaseLoad, PhaseValidation} {
+		if err := b.Phase(name, record(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := b.Start(context.Background())
+	if len(result.Errors.All()) != 0 {
+		t.Fatal(result.Errors.All())
+	}
+
+	expected := []string{"initialize", PhasePrepare, "warmup", PhaseLoad, PhaseValidation, "final-check"}
+	if len(executed) != len(expected) {
+		t.Fatalf("%v", executed)
+	}
+	for i, name := range expected {
+		if executed[i] != name {
+			t.Fatalf("%v", executed)
+		}
+	}
+}
+
+func TestBenchmarkPhaseErrors(t *testing.T) {
+	b := newBenchmark()
+
+	if err := b.AddPhase(PhaseLoad); !errors.Is(err, ErrPhaseDuplicated) {
+		t.Fatal(err)
+	}
+
+	if err := b.Phase("unknown", func(_ context.Context, _ *BenchmarkStep) error { return nil }); !errors.Is(err, ErrPhaseNotFound) {
+		t.Fatal(err)
+	}
+
+	if err := b.AddPhase("a", WithPhaseAfter("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.AddPhase("b", WithPhaseAfter("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	result := b.Start(context.Background())
+	errs := result.Errors.All()
+	if len(errs) != 1 || !failure.IsCode(errs[0], ErrPhase) || !failure.Is(errs[0], ErrPhaseCycle) {
+		t.Fatal(errs)
+	}
+}
+
+func TestBenchmarkPhaseTimeoutAndCode(t *testing.T) {
+	code := failure.StringCode("post-load")
+	b := newBenchmark()
+
+	if err := b.AddPhase("post-load", WithPhaseAfter(PhaseLoad), WithPhaseTimeout(5*time.Millisecond), WithPhaseErrorCode(code)); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Phase("post-load", func(ctx context.Context, _ *BenchmarkStep) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	validated := false
+	b.Validation(func(_ context.Context, _ *BenchmarkStep) error {
+		validated = true
+		return nil
+	})
+
+	result := b.Start(context.Background())
+	errs := result.Errors.All()
+	if len(errs) != 1 || !failure.IsCode(errs[0], code) || !failure.Is(errs[0], context.DeadlineExceeded) {
+		t.Fatal(errs)
+	}
+
+	if !validated {
+		t.Fatal("validation must run before post-load")
+	}
+}
@@ -0,0 +1,75 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	DefaultProgressInterval = 1 * time.Second
+
+	ErrInvalidProgressInterval = errors.New("Progress interval must be positive")
+)
+
+type BenchmarkProgress struct {
+	Phase     string           `json:"phase"`
+	Elapsed   time.Duration    `json:"elapsed"`
+	Score     int64            `json:"score"`
+	Breakdown map[string]int64 `json:"breakdown"`
+	Errors    map[string]int64 `json:"errors"`
+}
+
+type BenchmarkProgressHook func(*BenchmarkProgress, *BenchmarkStep)
+
+func (b *Benchmark) OnProgress(f BenchmarkProgressHook) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.progressHooks = append(b.progressHooks, f)
+}
+
+func (b *BenchmarkStep) Progress() *BenchmarkProgress {
+	progress := &BenchmarkProgress{
+		Phase:     b.Phase(),
+		Elapsed:   time.Since(b.result.StartedAt),
+		Score:     b.result.Score.Sum(),
+		Breakdown: make(map[string]int64),
+		Errors:    b.result.Errors.Count(),
+	}
+
+	for tag, count := range b.result.Score.Breakdown() {
+		progress.Breakdown[string(tag)] = count
+	}
+
+	return progress
+}
+
+func (b *Benchmark) reportProgress(ctx context.Context, step *BenchmarkStep) {
+	b.mu.Lock()
+	hooks := make([]BenchmarkProgressHook, len(b.progressHooks))
+	copy(hooks, b.progressHooks)
+	interval := b.progressInterval
+	b.mu.Unlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				progress := step.Progress()
+				for _, hook := range hooks {
+					hook(progress, step)
+				}
+			}
+		}
+	}()
+}
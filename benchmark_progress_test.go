@@ -0,0 +1,78 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBenchmarkProgress(t *testing.T) {
+	b := newBenchmark(WithProgressInterval(5 * time.Millisecond))
+
+	mu := sync.Mutex{}
+	progresses := []*BenchmarkProgress{}
+	b.OnProgress(func(p *BenchmarkProgress, _ *BenchmarkStep) {
+		mu.Lock()
+		defer mu.Unlock()
+		progresses = append(progresses, p)
+	})
+
+	b.Load(func(ctx context.Context, s *BenchmarkStep) error {
+		s.AddScore("load")
+		s.AddError(errors.New("load"))
+		<-time.After(30 * time.Millisecond)
+		return nil
+	})
+
+	b.Start(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(progresses) == 0 {
+		t.Fatal("progress not reported")
+	}
+
+	found := false
+	for _, p := range progresses {
+		if p.Phase == PhaseLoad && p.Breakdown["load"] == 1 && p.Errors[string(ErrLoad)] == 1 && p.Elapsed > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("%+v", progresses)
+	}
+
+	if _, err := NewBenchmark(WithProgressInterval(0)); err != ErrInvalidProgressInterval {
+		t.Fatal(err)
+	}
+}
+
+func TestBenchmarkStepPhase(t *testing.T) {
+	b := newBenchmark()
+
+	phases := map[string]string{}
+	mu := sync.Mutex{}
+	record := func(name string) BenchmarkStepFunc {
+		return func(_ context.Context, s *BenchmarkStep) error {
+			mu.Lock()
+			defer mu.Unlock()
+			phases[name] = s.Phase()
+			return nil
+		}
+	}
+
+	b.Prepare(record("prepare"))
+	b.Load(record("load"))
+	b.Validation(record("validation"))
+
+	b.Start(context.Background())
+
+	for name, phase := range phases {
+		if name != phase {
+			t.Fatalf("%s: %s", name, phase)
+		}
+	}
+}
@@ -2,18 +2,242 @@ package isucandar
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/isucon/isucandar/failure"
+	"github.com/isucon/isucandar/metrics"
 	"github.com/isucon/isucandar/score"
 )
 
+type BenchmarkStepStatus string
+
+const (
+	StepRunning   BenchmarkStepStatus = "running"
+	StepSucceeded BenchmarkStepStatus = "succeeded"
+	StepFailed    BenchmarkStepStatus = "failed"
+	StepTimedOut  BenchmarkStepStatus = "timeout"
+	StepCanceled  BenchmarkStepStatus = "canceled"
+	StepSkipped   BenchmarkStepStatus = "skipped"
+)
+
+type BenchmarkStepResult struct {
+	Phase      string
+	Name       string
+	Status     BenchmarkStepStatus
+	StartedAt  time.Time
+	Duration   time.Duration
+	Attempts   int
+	ErrorCount int64
+}
+
+func newBenchmarkStepResult(phase string, name string, status BenchmarkStepStatus) *BenchmarkStepResult {
+	return &BenchmarkStepResult{
+		Phase:      phase,
+		Name:       name,
+		Status:     status,
+		Attempts:   0,
+		ErrorCount: 0,
+	}
+}
+
+type BenchmarkPhaseStatus string
+
+const (
+	PhaseRunning   BenchmarkPhaseStatus = "running"
+	PhaseCompleted BenchmarkPhaseStatus = "completed"
+	PhaseAborted   BenchmarkPhaseStatus = "aborted"
+)
+
+type BenchmarkPhaseResult struct {
+	Name      string
+	Status    BenchmarkPhaseStatus
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+func newBenchmarkPhaseResult(name string) *BenchmarkPhaseResult {
+	return &BenchmarkPhaseResult{
+		Name:      name,
+		Status:    PhaseRunning,
+		StartedAt: time.Now(),
+	}
+}
+
+type BenchmarkScenarioResult struct {
+	Name       string
+	Iterations int64
+	ErrorCount int64
+
+	mu     sync.RWMutex
+	scores score.ScoreTable
+}
+
+func newBenchmarkScenarioResult(name string) *BenchmarkScenarioResult {
+	return &BenchmarkScenarioResult{
+		Name:       name,
+		Iterations: 0,
+		ErrorCount: 0,
+		mu:         sync.RWMutex{},
+		scores:     make(score.ScoreTable),
+	}
+}
+
+func (s *BenchmarkScenarioResult) addScore(tag score.ScoreTag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scores[tag]++
+}
+
+func (s *BenchmarkScenarioResult) Breakdown() score.ScoreTable {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	table := make(score.ScoreTable, len(s.scores))
+	for tag, count := range s.scores {
+		table[tag] = count
+	}
+	return table
+}
+
 type BenchmarkResult struct {
-	Score  *score.Score
-	Errors *failure.Errors
+	Score      *score.Score
+	Errors     *failure.Errors
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Verdict    *Verdict
+	Seed       int64
+	// agent.WithMetrics に渡すとエンドポイントごとのレイテンシが集計されます
+	Metrics *metrics.Registry
+
+	mu          sync.RWMutex
+	abortReason string
+	phases      []*BenchmarkPhaseResult
+	scenarios   map[string]*BenchmarkScenarioResult
+	steps       []*BenchmarkStepResult
 }
 
 func newBenchmarkResult(ctx context.Context) *BenchmarkResult {
 	return &BenchmarkResult{
-		Score:  score.NewScore(ctx),
-		Errors: failure.NewErrors(ctx),
+		Score:     score.NewScore(ctx),
+		Errors:    failure.NewErrors(ctx),
+		StartedAt: time.Now(),
+		Metrics:   metrics.NewRegistry(),
+		mu:        sync.RWMutex{},
+		phases:    []*BenchmarkPhaseResult{},
+		scenarios: make(map[string]*BenchmarkScenarioResult),
+		steps:     []*BenchmarkStepResult{},
+	}
+}
+
+func (r *BenchmarkResult) abort(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.abortReason == "" {
+		r.abortReason = reason
 	}
 }
+
+// ErrorPolicy によって中断された場合、その理由を返します
+func (r *BenchmarkResult) AbortReason() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.abortReason
+}
+
+// 同じ名前のシナリオは同じ BenchmarkScenarioResult に集計されます
+func (r *BenchmarkResult) scenarioResult(name string) *BenchmarkScenarioResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if scenario, found := r.scenarios[name]; found {
+		return scenario
+	}
+
+	scenario := newBenchmarkScenarioResult(name)
+	r.scenarios[name] = scenario
+	return scenario
+}
+
+func (r *BenchmarkResult) Scenarios() map[string]*BenchmarkScenarioResult {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	scenarios := make(map[string]*BenchmarkScenarioResult, len(r.scenarios))
+	for name, scenario := range r.scenarios {
+		scenarios[name] = scenario
+	}
+	return scenarios
+}
+
+func (r *BenchmarkResult) addPhaseResult(phase *BenchmarkPhaseResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.phases = append(r.phases, phase)
+}
+
+func (r *BenchmarkResult) Phases() []*BenchmarkPhaseResult {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	phases := make([]*BenchmarkPhaseResult, len(r.phases))
+	copy(phases, r.phases)
+
+	return phases
+}
+
+// すべてのフェーズが中断されずに完了したかどうかを返します
+func (r *BenchmarkResult) Completed() bool {
+	phases := r.Phases()
+	if len(phases) == 0 {
+		return false
+	}
+
+	for _, phase := range phases {
+		if phase.Status != PhaseCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *BenchmarkResult) addStepResult(step *BenchmarkStepResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.steps = append(r.steps, step)
+}
+
+// ステップは Start の終了後も実行中の場合があるため、更新はロックを取って行います
+func (r *BenchmarkResult) updateStepResult(step *BenchmarkStepResult, f func(*BenchmarkStepResult)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f(step)
+}
+
+// Steps は各ステップの結果のコピーを返します
+func (r *BenchmarkResult) Steps() []*BenchmarkStepResult {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	steps := make([]*BenchmarkStepResult, 0, len(r.steps))
+	for _, step := range r.steps {
+		steps = append(steps, &BenchmarkStepResult{
+			Phase:      step.Phase,
+			Name:       step.Name,
+			Status:     step.Status,
+			StartedAt:  step.StartedAt,
+			Duration:   step.Duration,
+			Attempts:   step.Attempts,
+			ErrorCount: atomic.LoadInt64(&step.ErrorCount),
+		})
+	}
+
+	return steps
+}
@@ -0,0 +1,132 @@
+package isucandar
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+type BenchmarkStats struct {
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+func newBenchmarkStats(values []float64) BenchmarkStats {
+	stats := BenchmarkStats{}
+	if len(values) == 0 {
+		return stats
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	sum := float64(0)
+	for _, v := range sorted {
+		sum += v
+	}
+	stats.Mean = sum / float64(len(sorted))
+
+	variance := float64(0)
+	for _, v := range sorted {
+		variance += (v - stats.Mean) * (v - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(len(sorted)))
+
+	if n := len(sorted); n%2 == 0 {
+		stats.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	} else {
+		stats.Median = sorted[n/2]
+	}
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+
+	return stats
+}
+
+type BenchmarkRuns struct {
+	Results      []*BenchmarkResult        `json:"results"`
+	Score        BenchmarkStats            `json:"score"`
+	Errors       BenchmarkStats            `json:"errors"`
+	ErrorsByCode map[string]BenchmarkStats `json:"errors_by_code"`
+}
+
+// BenchmarkRunSetup は Repeat の各走行の直前に呼ばれます。run は 0 から始まる走行番号です
+type BenchmarkRunSetup func(ctx context.Context, run int)
+
+type repeatConfig struct {
+	setups []BenchmarkRunSetup
+}
+
+type RepeatOption func(*repeatConfig)
+
+// WithRunSetup は走行ごとに LoadMix や vuser.Pool、Agent の Cookie やキャッシュなど
+// 前回の走行から持ち越される状態を作り直すための関数を登録します
+func WithRunSetup(f BenchmarkRunSetup) RepeatOption {
+	return func(c *repeatConfig) {
+		c.setups = append(c.setups, f)
+	}
+}
+
+// Repeat は Start を count 回順に実行し、スコアとエラー数のばらつきを集計します。
+// Criteria が設定されている場合、スコアには Verdict の最終スコアを用います。
+func (b *Benchmark) Repeat(ctx context.Context, count int, interval time.Duration, opts ...RepeatOption) *BenchmarkRuns {
+	config := &repeatConfig{
+		setups: []BenchmarkRunSetup{},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	runs := &BenchmarkRuns{
+		Results:      []*BenchmarkResult{},
+		ErrorsByCode: make(map[string]BenchmarkStats),
+	}
+
+	for i := 0; i < count && ctx.Err() == nil; i++ {
+		if i > 0 && interval > 0 {
+			select {
+			case <-ctx.Done():
+				continue
+			case <-time.After(interval):
+			}
+		}
+
+		for _, setup := range config.setups {
+			setup(ctx, i)
+		}
+
+		runs.Results = append(runs.Results, b.Start(ctx))
+	}
+
+	scores := make([]float64, 0, len(runs.Results))
+	errors := make([]float64, 0, len(runs.Results))
+	codes := make(map[string][]float64)
+	for _, result := range runs.Results {
+		if result.Verdict != nil {
+			scores = append(scores, float64(result.Verdict.Score))
+		} else {
+			scores = append(scores, float64(result.Score.Sum()))
+		}
+		errors = append(errors, float64(len(result.Errors.All())))
+
+		for code := range result.Errors.Count() {
+			codes[code] = nil
+		}
+	}
+
+	for code := range codes {
+		for _, result := range runs.Results {
+			codes[code] = append(codes[code], float64(result.Errors.Count()[code]))
+		}
+		runs.ErrorsByCode[code] = newBenchmarkStats(codes[code])
+	}
+	runs.Score = newBenchmarkStats(scores)
+	runs.Errors = newBenchmarkStats(errors)
+
+	return runs
+}
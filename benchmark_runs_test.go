@@ -0,0 +1,98 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestBenchmarkStats(t *testing.T) {
+	stats := newBenchmarkStats([]float64{4, 2, 9, 5})
+
+	if stats.Mean != 5 || stats.Median != 4.5 || stats.Min != 2 || stats.Max != 9 {
+		t.Fatalf("%+v", stats)
+	}
+
+	if math.Abs(stats.StdDev-math.Sqrt(6.5)) > 1e-9 {
+		t.Fatalf("%+v", stats)
+	}
+
+	if stats := newBenchmarkStats([]float64{3, 1, 2}); stats.Median != 2 {
+		t.Fatalf("%+v", stats)
+	}
+
+	if stats := newBenchmarkStats(nil); stats != (BenchmarkStats{}) {
+		t.Fatalf("%+v", stats)
+	}
+}
+
+func TestBenchmarkRepeat(t *testing.T) {
+	b := newBenchmark()
+
+	run := 0
+	b.Load(func(_ context.Context, s *BenchmarkStep) error {
+		run++
+		s.Result().Score.Set("score", 10)
+		for i := 0; i < run; i++ {
+			s.AddScore("score")
+			s.AddError(errors.New("error"))
+		}
+		return nil
+	})
+
+	runs := b.Repeat(context.Background(), 3, 0)
+
+	if len(runs.Results) != 3 {
+		t.Fatal(len(runs.Results))
+	}
+
+	if runs.Score.Mean != 20 || runs.Score.Min != 10 || runs.Score.Max != 30 {
+		t.Fatalf("%+v", runs.Score)
+	}
+
+	if runs.Errors.Median != 2 || runs.ErrorsByCode[string(ErrLoad)].Max != 3 {
+		t.Fatalf("%+v %+v", runs.Errors, runs.ErrorsByCode)
+	}
+}
+
+func TestBenchmarkRepeatCanceled(t *testing.T) {
+	b := newBenchmark()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if runs := b.Repeat(ctx, 3, 0); len(runs.Results) != 0 {
+		t.Fatal(len(runs.Results))
+	}
+}
+
+func TestBenchmarkRepeatWithRunSetup(t *testing.T) {
+	b := newBenchmark()
+
+	state := 0
+	b.Load(func(_ context.Context, s *BenchmarkStep) error {
+		state++
+		s.Result().Score.Set("score", 1)
+		for i := 0; i < state; i++ {
+			s.AddScore("score")
+		}
+		return nil
+	})
+
+	setups := []int{}
+	reset := func(_ context.Context, run int) {
+		setups = append(setups, run)
+		state = 0
+	}
+
+	runs := b.Repeat(context.Background(), 3, 0, WithRunSetup(reset))
+
+	if len(setups) != 3 || setups[0] != 0 || setups[2] != 2 {
+		t.Fatal(setups)
+	}
+
+	if runs.Score.Min != 1 || runs.Score.Max != 1 {
+		t.Fatalf("%+v", runs.Score)
+	}
+}
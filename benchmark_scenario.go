@@ -21,24 +21,48 @@ type ValidationScenario interface {
 	Validation(context.Context, *BenchmarkStep) error
 }
 
+// Scenario はフェーズごとに実行するステップをまとめたものです。実行しないフェーズは nil にします。
+//
+//	isucandar.Scenario{Prepare: s.Prepare, Load: s.Load}
+type Scenario struct {
+	Prepare    BenchmarkStepFunc
+	Load       BenchmarkStepFunc
+	Validation BenchmarkStepFunc
+}
+
+func (s Scenario) empty() bool {
+	return s.Prepare == nil && s.Load == nil && s.Validation == nil
+}
+
 func (b *Benchmark) AddScenario(scenario interface{}) {
-	match := false
+	s := Scenario{}
 	if p, ok := scenario.(PrepareScenario); ok {
-		b.Prepare(p.Prepare)
-		match = true
+		s.Prepare = p.Prepare
 	}
-
 	if l, ok := scenario.(LoadScenario); ok {
-		b.Load(l.Load)
-		match = true
+		s.Load = l.Load
 	}
-
 	if v, ok := scenario.(ValidationScenario); ok {
-		b.Validation(v.Validation)
-		match = true
+		s.Validation = v.Validation
 	}
 
-	if !match {
+	if s.empty() {
 		panic(ErrInvalidScenario)
 	}
+
+	b.addScenario(s)
+}
+
+func (b *Benchmark) addScenario(s Scenario, opts ...BenchmarkStepOption) {
+	if s.Prepare != nil {
+		b.Prepare(s.Prepare, opts...)
+	}
+
+	if s.Load != nil {
+		b.Load(s.Load, opts...)
+	}
+
+	if s.Validation != nil {
+		b.Validation(s.Validation, opts...)
+	}
 }
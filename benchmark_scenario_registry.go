@@ -0,0 +1,358 @@
+package isucandar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	ErrScenarioDuplicated = errors.New("Scenario already registered")
+	ErrScenarioNotFound   = errors.New("Scenario not found")
+)
+
+type ScenarioOption func(*ScenarioEntry) error
+
+type ScenarioEntry struct {
+	Name     string
+	Scenario Scenario
+
+	mu       sync.RWMutex
+	weight   int
+	enabled  bool
+	children []*ScenarioEntry
+}
+
+func NewScenarioEntry(name string, scenario Scenario, opts ...ScenarioOption) (*ScenarioEntry, error) {
+	if scenario.empty() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidScenario, name)
+	}
+
+	entry := &ScenarioEntry{
+		Name:     name,
+		Scenario: scenario,
+		mu:       sync.RWMutex{},
+		weight:   1,
+		enabled:  true,
+	}
+
+	for _, opt := range opts {
+		if err := opt(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+func WithScenarioWeight(weight int) ScenarioOption {
+	return func(e *ScenarioEntry) error {
+		e.weight = weight
+		return nil
+	}
+}
+
+func WithScenarioDisabled() ScenarioOption {
+	return func(e *ScenarioEntry) error {
+		e.enabled = false
+		return nil
+	}
+}
+
+// 重みは LoadMix でシナリオを選ぶ際にのみ利用されます
+func (e *ScenarioEntry) Weight() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.weight
+}
+
+func (e *ScenarioEntry) SetWeight(weight int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.weight = weight
+}
+
+func (e *ScenarioEntry) Enabled() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.enabled
+}
+
+func (e *ScenarioEntry) SetEnabled(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.enabled = enabled
+}
+
+// 複合シナリオは子シナリオが実装しているフェーズのみを持ちます。
+// Prepare と Validation は登録順に、Load は並列に実行されます。
+// 無効化された子シナリオは実行されません。
+func newCompositeScenario(entries []*ScenarioEntry) Scenario {
+	var prepare, load, validation bool
+	for _, entry := range entries {
+		prepare = prepare || entry.Scenario.Prepare != nil
+		load = load || entry.Scenario.Load != nil
+		validation = validation || entry.Scenario.Validation != nil
+	}
+
+	composite := Scenario{}
+	if prepare {
+		composite.Prepare = func(ctx context.Context, step *BenchmarkStep) error {
+			for _, entry := range entries {
+				if entry.Scenario.Prepare != nil && entry.Enabled() {
+					if err := entry.Scenario.Prepare(ctx, step); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+	}
+
+	if load {
+		composite.Load = func(ctx context.Context, step *BenchmarkStep) error {
+			wg := sync.WaitGroup{}
+			for _, entry := range entries {
+				if entry.Scenario.Load != nil && entry.Enabled() {
+					wg.Add(1)
+					go func(f BenchmarkStepFunc) {
+						defer wg.Done()
+						if err := panicWrapper(step.panicRecover, func() error { return f(ctx, step) }); err != nil {
+							step.AddError(err)
+						}
+					}(entry.Scenario.Load)
+				}
+			}
+			wg.Wait()
+			return nil
+		}
+	}
+
+	if validation {
+		composite.Validation = func(ctx context.Context, step *BenchmarkStep) error {
+			for _, entry := range entries {
+				if entry.Scenario.Validation != nil && entry.Enabled() {
+					if err := entry.Scenario.Validation(ctx, step); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+	}
+
+	return composite
+}
+
+type ScenarioRegistry struct {
+	mu      sync.RWMutex
+	entries []*ScenarioEntry
+	index   map[string]*ScenarioEntry
+}
+
+func NewScenarioRegistry() *ScenarioRegistry {
+	return &ScenarioRegistry{
+		mu:      sync.RWMutex{},
+		entries: []*ScenarioEntry{},
+		index:   make(map[string]*ScenarioEntry),
+	}
+}
+
+func (r *ScenarioRegistry) Register(name string, scenario Scenario, opts ...ScenarioOption) error {
+	entry, err := NewScenarioEntry(name, scenario, opts...)
+	if err != nil {
+		return err
+	}
+
+	return r.add(entry)
+}
+
+func (r *ScenarioRegistry) add(entry *ScenarioEntry) error {
+	name := entry.Name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.index[name]; found {
+		return fmt.Errorf("%w: %s", ErrScenarioDuplicated, name)
+	}
+
+	r.entries = append(r.entries, entry)
+	r.index[name] = entry
+
+	return nil
+}
+
+// Compose は登録済みのシナリオを束ねた複合シナリオを name で登録します。
+// 子シナリオの有効・無効は複合シナリオの中で実行するかどうかを表し、
+// 有効な複合シナリオに含まれる子シナリオは単独では実行されません。
+func (r *ScenarioRegistry) Compose(name string, children []string, opts ...ScenarioOption) error {
+	entries := make([]*ScenarioEntry, 0, len(children))
+	for _, child := range children {
+		entry, found := r.Get(child)
+		if !found {
+			return fmt.Errorf("%w: %s", ErrScenarioNotFound, child)
+		}
+		entries = append(entries, entry)
+	}
+
+	entry, err := NewScenarioEntry(name, newCompositeScenario(entries), opts...)
+	if err != nil {
+		return err
+	}
+	entry.children = entries
+
+	return r.add(entry)
+}
+
+func (r *ScenarioRegistry) Get(name string) (*ScenarioEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, found := r.index[name]
+	return entry, found
+}
+
+func (r *ScenarioRegistry) All() []*ScenarioEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]*ScenarioEntry, len(r.entries))
+	copy(entries, r.entries)
+
+	return entries
+}
+
+func (r *ScenarioRegistry) Enabled() []*ScenarioEntry {
+	entries := []*ScenarioEntry{}
+	for _, entry := range r.All() {
+		if entry.Enabled() {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// 有効なシナリオのうち、有効な複合シナリオに含まれないものを返します
+func (r *ScenarioRegistry) topLevel() []*ScenarioEntry {
+	enabled := r.Enabled()
+
+	members := make(map[*ScenarioEntry]bool)
+	var mark func(entry *ScenarioEntry)
+	mark = func(entry *ScenarioEntry) {
+		for _, child := range entry.children {
+			if !members[child] {
+				members[child] = true
+				mark(child)
+			}
+		}
+	}
+	for _, entry := range enabled {
+		mark(entry)
+	}
+
+	entries := make([]*ScenarioEntry, 0, len(enabled))
+	for _, entry := range enabled {
+		if !members[entry] {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (r *ScenarioRegistry) Enable(names ...string) error {
+	return r.setEnabled(true, names...)
+}
+
+func (r *ScenarioRegistry) Disable(names ...string) error {
+	return r.setEnabled(false, names...)
+}
+
+func (r *ScenarioRegistry) setEnabled(enabled bool, names ...string) error {
+	for _, name := range names {
+		entry, found := r.Get(name)
+		if !found {
+			return fmt.Errorf("%w: %s", ErrScenarioNotFound, name)
+		}
+		entry.SetEnabled(enabled)
+	}
+	return nil
+}
+
+// Select は names で指定したシナリオのみを有効にします
+func (r *ScenarioRegistry) Select(names ...string) error {
+	for _, name := range names {
+		if _, found := r.Get(name); !found {
+			return fmt.Errorf("%w: %s", ErrScenarioNotFound, name)
+		}
+	}
+
+	for _, entry := range r.All() {
+		entry.SetEnabled(false)
+	}
+
+	return r.Enable(names...)
+}
+
+type ScenarioConfig struct {
+	Enabled *bool `json:"enabled,omitempty"`
+	Weight  *int  `json:"weight,omitempty"`
+}
+
+type ScenarioRegistryConfig struct {
+	Select    []string                  `json:"select,omitempty"`
+	Scenarios map[string]ScenarioConfig `json:"scenarios,omitempty"`
+}
+
+// LoadConfig は以下のような JSON を読み込み、シナリオの有効・無効と重みを設定します。
+//
+//	{"select": ["browse", "search"], "scenarios": {"search": {"weight": 3}}}
+func (r *ScenarioRegistry) LoadConfig(reader io.Reader) error {
+	config := &ScenarioRegistryConfig{}
+	if err := json.NewDecoder(reader).Decode(config); err != nil {
+		return err
+	}
+
+	return r.ApplyConfig(config)
+}
+
+func (r *ScenarioRegistry) ApplyConfig(config *ScenarioRegistryConfig) error {
+	if len(config.Select) > 0 {
+		if err := r.Select(config.Select...); err != nil {
+			return err
+		}
+	}
+
+	for name, c := range config.Scenarios {
+		entry, found := r.Get(name)
+		if !found {
+			return fmt.Errorf("%w: %s", ErrScenarioNotFound, name)
+		}
+
+		if c.Enabled != nil {
+			entry.SetEnabled(*c.Enabled)
+		}
+		if c.Weight != nil {
+			entry.SetWeight(*c.Weight)
+		}
+	}
+
+	return nil
+}
+
+// AddScenarios は有効なシナリオをベンチマークに登録します。
+// 有効な複合シナリオに含まれるシナリオは、その複合シナリオの中でのみ実行されます。
+// シナリオ名はステップ名として利用されます。
+// 重みはステップの実行には影響しません。重みに従って実行する場合は LoadMix.AddScenarios を利用してください。
+func (b *Benchmark) AddScenarios(r *ScenarioRegistry) {
+	for _, entry := range r.topLevel() {
+		b.addScenario(entry.Scenario, WithStepName(entry.Name))
+	}
+}
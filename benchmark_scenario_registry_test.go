@@ -0,0 +1,221 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+type loadOnlyScenario struct {
+	count uint32
+}
+
+func (l *loadOnlyScenario) Load(_ context.Context, _ *BenchmarkStep) error {
+	atomic.AddUint32(&l.count, 1)
+	return nil
+}
+
+func (l *loadOnlyScenario) scenario() Scenario {
+	return Scenario{Load: l.Load}
+}
+
+func TestScenarioRegistry(t *testing.T) {
+	registry := NewScenarioRegistry()
+
+	browse := &loadOnlyScenario{}
+	search := &loadOnlyScenario{}
+	purchase := &exampleScenario{}
+
+	if err := registry.Register("browse", browse.scenario(), WithScenarioWeight(6)); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register("search", search.scenario(), WithScenarioWeight(3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register("purchase", purchase.scenario(), WithScenarioDisabled()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := registry.Register("browse", browse.scenario()); !errors.Is(err, ErrScenarioDuplicated) {
+		t.Fatal(err)
+	}
+	if err := registry.Register("invalid", Scenario{}); !errors.Is(err, ErrInvalidScenario) {
+		t.Fatal(err)
+	}
+	if err := registry.Enable("unknown"); !errors.Is(err, ErrScenarioNotFound) {
+		t.Fatal(err)
+	}
+
+	if entry, _ := registry.Get("browse"); entry.Weight() != 6 || !entry.Enabled() {
+		t.Fatalf("%+v", entry)
+	}
+
+	if enabled := registry.Enabled(); len(enabled) != 2 {
+		t.Fatal(enabled)
+	}
+
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddScenarios(registry)
+	result := b.Start(context.Background())
+
+	if browse.count != 1 || search.count != 1 || purchase.load != 0 {
+		t.Fatal(browse.count, search.count, purchase.load)
+	}
+
+	if findStepResult(result, "browse") == nil || findStepResult(result, "purchase") != nil {
+		t.Fatal(result.Steps())
+	}
+}
+
+func TestScenarioRegistryConfig(t *testing.T) {
+	registry := NewScenarioRegistry()
+	registry.Register("browse", (&loadOnlyScenario{}).scenario())
+	registry.Register("search", (&loadOnlyScenario{}).scenario())
+	registry.Register("purchase", (&loadOnlyScenario{}).scenario())
+
+	config := `{"select": ["browse", "search"], "scenarios": {"search": {"weight": 3}, "browse": {"enabled": false}}}`
+	if err := registry.LoadConfig(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+
+	enabled := registry.Enabled()
+	if len(enabled) != 1 || enabled[0].Name != "search" || enabled[0].Weight() != 3 {
+		t.Fatal(enabled)
+	}
+
+	if err := registry.LoadConfig(strings.NewReader(`{"select": ["unknown"]}`)); !errors.Is(err, ErrScenarioNotFound) {
+		t.Fatal(err)
+	}
+
+	if err := registry.LoadConfig(strings.NewReader(`{`)); err == nil {
+		t.Fatal("invalid json accepted")
+	}
+}
+
+func TestScenarioRegistryCompose(t *testing.T) {
+	registry := NewScenarioRegistry()
+
+	browse := &loadOnlyScenario{}
+	purchase := &exampleScenario{}
+	registry.Register("browse", browse.scenario(), WithScenarioDisabled())
+	registry.Register("purchase", purchase.scenario(), WithScenarioDisabled())
+
+	if err := registry.Compose("shopping", []string{"browse", "purchase", "unknown"}); !errors.Is(err, ErrScenarioNotFound) {
+		t.Fatal(err)
+	}
+	if err := registry.Compose("shopping", []string{"browse", "purchase"}); err != nil {
+		t.Fatal(err)
+	}
+	registry.Select("shopping")
+
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddScenarios(registry)
+	b.Start(context.Background())
+
+	// 子シナリオが無効化されている場合は実行されません
+	if browse.count != 0 || purchase.prepare != 0 {
+		t.Fatal(browse.count, purchase.prepare)
+	}
+
+	registry.Enable("browse", "purchase")
+	b.Start(context.Background())
+
+	if browse.count != 1 || purchase.prepare != 1 || purchase.load != 1 || purchase.validation != 1 {
+		t.Fatal(browse.count, purchase)
+	}
+}
+
+func TestScenarioRegistryComposeLoadOnly(t *testing.T) {
+	registry := NewScenarioRegistry()
+
+	registry.Register("browse", (&loadOnlyScenario{}).scenario(), WithScenarioDisabled())
+	registry.Register("search", (&loadOnlyScenario{}).scenario(), WithScenarioDisabled())
+	if err := registry.Compose("visitor", []string{"browse", "search"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Compose("empty", []string{}); !errors.Is(err, ErrInvalidScenario) {
+		t.Fatal(err)
+	}
+
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddScenarios(registry)
+	result := b.Start(context.Background())
+
+	steps := result.Steps()
+	if len(steps) != 1 || steps[0].Phase != PhaseLoad || steps[0].Name != "visitor" {
+		t.Fatalf("%+v", steps)
+	}
+}
+
+func TestScenarioRegistryComposePanic(t *testing.T) {
+	registry := NewScenarioRegistry()
+
+	registry.Register("browse", (&loadOnlyScenario{}).scenario(), WithScenarioDisabled())
+	registry.Register("broken", Scenario{Load: func(_ context.Context, _ *BenchmarkStep) error {
+		panic("composite child panic")
+	}}, WithScenarioDisabled())
+	if err := registry.Compose("visitor", []string{"browse", "broken"}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddScenarios(registry)
+
+	// 子シナリオのパニックもステップと同様にエラーとして記録されます
+	registry.Enable("broken")
+	result := b.Start(context.Background())
+
+	errs := result.Errors.All()
+	if len(errs) != 1 || !failure.IsCode(errs[0], ErrPanic) {
+		t.Fatal(errs)
+	}
+}
+
+func TestScenarioRegistryComposeEnabledChildren(t *testing.T) {
+	registry := NewScenarioRegistry()
+
+	browse := &loadOnlyScenario{}
+	search := &loadOnlyScenario{}
+	registry.Register("browse", browse.scenario())
+	registry.Register("search", search.scenario())
+	if err := registry.Compose("all", []string{"browse"}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.AddScenarios(registry)
+	b.Start(context.Background())
+
+	// 有効な複合シナリオに含まれる子シナリオは1回だけ実行されます
+	if browse.count != 1 || search.count != 1 {
+		t.Fatal(browse.count, search.count)
+	}
+
+	mix, err := NewLoadMix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mix.AddScenarios(registry)
+	if weights := mix.Weights(); len(weights) != 2 || weights["all"] != 1 || weights["search"] != 1 {
+		t.Fatal(weights)
+	}
+}
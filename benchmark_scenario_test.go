@@ -27,6 +27,10 @@ func (e *exampleScenario) Validation(_ context.Context, _ *BenchmarkStep) error
 	return nil
 }
 
+func (e *exampleScenario) scenario() Scenario {
+	return Scenario{Prepare: e.Prepare, Load: e.Load, Validation: e.Validation}
+}
+
 func TestBenchmarkAddScenario(t *testing.T) {
 	benchmark, err := NewBenchmark()
 	if err != nil {
@@ -0,0 +1,204 @@
+package isucandar
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/isucon/isucandar/metrics"
+)
+
+// BenchmarkSnapshot は BenchmarkResult をシリアライズ可能な形に固めたものです。
+// JSON のフィールド名は proto/benchmark_result.proto と対応しています。
+type BenchmarkSnapshot struct {
+	StartedAt   time.Time                   `json:"started_at"`
+	FinishedAt  time.Time                   `json:"finished_at"`
+	Passed      bool                        `json:"passed"`
+	Seed        int64                       `json:"seed"`
+	AbortReason string                      `json:"abort_reason,omitempty"`
+	Score       BenchmarkScoreSnapshot      `json:"score"`
+	Errors      BenchmarkErrorsSnapshot     `json:"errors"`
+	Phases      []BenchmarkPhaseSnapshot    `json:"phases"`
+	Steps       []BenchmarkStepSnapshot     `json:"steps"`
+	Scenarios   []BenchmarkScenarioSnapshot `json:"scenarios"`
+	Endpoints   []BenchmarkEndpointSnapshot `json:"endpoints"`
+	Verdict     *Verdict                    `json:"verdict,omitempty"`
+}
+
+type BenchmarkScoreSnapshot struct {
+	Total     int64            `json:"total"`
+	Breakdown map[string]int64 `json:"breakdown"`
+	Table     map[string]int64 `json:"table"`
+}
+
+type BenchmarkErrorsSnapshot struct {
+	Total    int64               `json:"total"`
+	Count    map[string]int64    `json:"count"`
+	Messages map[string][]string `json:"messages"`
+}
+
+type BenchmarkPhaseSnapshot struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type BenchmarkStepSnapshot struct {
+	Phase      string    `json:"phase"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Attempts   int       `json:"attempts"`
+	ErrorCount int64     `json:"error_count"`
+}
+
+type BenchmarkScenarioSnapshot struct {
+	Name       string           `json:"name"`
+	Iterations int64            `json:"iterations"`
+	ErrorCount int64            `json:"error_count"`
+	Breakdown  map[string]int64 `json:"breakdown"`
+}
+
+type BenchmarkLatencySnapshot struct {
+	Count  int64   `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+type BenchmarkEndpointSnapshot struct {
+	Key     string                   `json:"key"`
+	Count   int64                    `json:"count"`
+	Errors  int64                    `json:"errors"`
+	Retries int64                    `json:"retries"`
+	DNS     BenchmarkLatencySnapshot `json:"dns"`
+	Connect BenchmarkLatencySnapshot `json:"connect"`
+	TLS     BenchmarkLatencySnapshot `json:"tls"`
+	TTFB    BenchmarkLatencySnapshot `json:"ttfb"`
+	Total   BenchmarkLatencySnapshot `json:"total"`
+}
+
+func newBenchmarkLatencySnapshot(s metrics.HistogramSummary) BenchmarkLatencySnapshot {
+	ms := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+
+	return BenchmarkLatencySnapshot{
+		Count:  s.Count,
+		MeanMs: ms(s.Mean),
+		P50Ms:  ms(s.P50),
+		P90Ms:  ms(s.P90),
+		P99Ms:  ms(s.P99),
+		MaxMs:  ms(s.Max),
+	}
+}
+
+func (r *BenchmarkResult) Snapshot() *BenchmarkSnapshot {
+	snapshot := &BenchmarkSnapshot{
+		StartedAt:   r.StartedAt,
+		FinishedAt:  r.FinishedAt,
+		Passed:      r.Completed(),
+		Seed:        r.Seed,
+		AbortReason: r.AbortReason(),
+		Score: BenchmarkScoreSnapshot{
+			Total:     r.Score.Sum(),
+			Breakdown: make(map[string]int64),
+			Table:     make(map[string]int64),
+		},
+		Errors: BenchmarkErrorsSnapshot{
+			Total:    int64(len(r.Errors.All())),
+			Count:    r.Errors.Count(),
+			Messages: r.Errors.Messages(),
+		},
+		Phases:    []BenchmarkPhaseSnapshot{},
+		Steps:     []BenchmarkStepSnapshot{},
+		Scenarios: []BenchmarkScenarioSnapshot{},
+		Endpoints: []BenchmarkEndpointSnapshot{},
+		Verdict:   r.Verdict,
+	}
+
+	if r.Verdict != nil {
+		snapshot.Passed = r.Verdict.Passed
+	}
+
+	for tag, count := range r.Score.Breakdown() {
+		snapshot.Score.Breakdown[string(tag)] = count
+	}
+	for tag, mag := range r.Score.Table {
+		snapshot.Score.Table[string(tag)] = mag
+	}
+
+	for _, phase := range r.Phases() {
+		snapshot.Phases = append(snapshot.Phases, BenchmarkPhaseSnapshot{
+			Name:       phase.Name,
+			Status:     string(phase.Status),
+			StartedAt:  phase.StartedAt,
+			DurationMs: phase.Duration.Milliseconds(),
+		})
+	}
+
+	for _, step := range r.Steps() {
+		snapshot.Steps = append(snapshot.Steps, BenchmarkStepSnapshot{
+			Phase:      step.Phase,
+			Name:       step.Name,
+			Status:     string(step.Status),
+			StartedAt:  step.StartedAt,
+			DurationMs: step.Duration.Milliseconds(),
+			Attempts:   step.Attempts,
+			ErrorCount: step.ErrorCount,
+		})
+	}
+
+	scenarios := r.Scenarios()
+	names := make([]string, 0, len(scenarios))
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		scenario := scenarios[name]
+		breakdown := make(map[string]int64)
+		for tag, count := range scenario.Breakdown() {
+			breakdown[string(tag)] = count
+		}
+
+		snapshot.Scenarios = append(snapshot.Scenarios, BenchmarkScenarioSnapshot{
+			Name:       scenario.Name,
+			Iterations: atomic.LoadInt64(&scenario.Iterations),
+			ErrorCount: atomic.LoadInt64(&scenario.ErrorCount),
+			Breakdown:  breakdown,
+		})
+	}
+
+	if r.Metrics != nil {
+		for _, endpoint := range r.Metrics.Summary() {
+			snapshot.Endpoints = append(snapshot.Endpoints, BenchmarkEndpointSnapshot{
+				Key:     endpoint.Key,
+				Count:   endpoint.Count,
+				Errors:  endpoint.Errors,
+				Retries: endpoint.Retries,
+				DNS:     newBenchmarkLatencySnapshot(endpoint.DNS),
+				Connect: newBenchmarkLatencySnapshot(endpoint.Connect),
+				TLS:     newBenchmarkLatencySnapshot(endpoint.TLS),
+				TTFB:    newBenchmarkLatencySnapshot(endpoint.TTFB),
+				Total:   newBenchmarkLatencySnapshot(endpoint.Total),
+			})
+		}
+	}
+
+	return snapshot
+}
+
+func (r *BenchmarkResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Snapshot())
+}
+
+func (s *BenchmarkSnapshot) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
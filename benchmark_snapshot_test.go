@@ -0,0 +1,95 @@
+package isucandar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/metrics"
+)
+
+func TestBenchmarkSnapshot(t *testing.T) {
+	b := newBenchmark()
+
+	b.Load(func(_ context.Context, s *BenchmarkStep) error {
+		s.AddScore("get")
+		s.AddScore("get")
+		s.AddScore("post")
+		s.AddError(errors.New("load error"))
+		s.Result().Metrics.Record("GET /items/:id", metrics.Timing{TTFB: time.Millisecond, Total: 2 * time.Millisecond})
+		return nil
+	}, WithStepName("scenario"))
+
+	result := b.Start(context.Background())
+	result.Score.Set("get", 1)
+	result.Score.Set("post", 5)
+
+	snapshot := result.Snapshot()
+
+	if !snapshot.Passed {
+		t.Fatal("not passed")
+	}
+
+	if snapshot.StartedAt.IsZero() || snapshot.FinishedAt.Before(snapshot.StartedAt) {
+		t.Fatal(snapshot.StartedAt, snapshot.FinishedAt)
+	}
+
+	if snapshot.Score.Total != 7 || snapshot.Score.Breakdown["get"] != 2 || snapshot.Score.Table["post"] != 5 {
+		t.Fatalf("%+v", snapshot.Score)
+	}
+
+	if snapshot.Errors.Total != 1 || snapshot.Errors.Count[string(ErrLoad)] != 1 || len(snapshot.Errors.Messages[string(ErrLoad)]) != 1 {
+		t.Fatalf("%+v", snapshot.Errors)
+	}
+
+	if len(snapshot.Phases) != 3 || snapshot.Phases[1].Name != PhaseLoad || snapshot.Phases[1].Status != string(PhaseCompleted) {
+		t.Fatalf("%+v", snapshot.Phases)
+	}
+
+	if len(snapshot.Endpoints) != 1 || snapshot.Endpoints[0].Key != "GET /items/:id" || snapshot.Endpoints[0].Total.MaxMs != 2 {
+		t.Fatalf("%+v", snapshot.Endpoints)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := snapshot.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &BenchmarkSnapshot{}
+	if err := json.Unmarshal(buf.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Score.Total != 7 || len(decoded.Steps) != len(snapshot.Steps) || decoded.Steps[0].Phase != PhasePrepare {
+		t.Fatalf("%+v", decoded)
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(buf.Bytes()), raw) {
+		t.Fatalf("%s\n%s", buf.Bytes(), raw)
+	}
+}
+
+func TestBenchmarkSnapshotAborted(t *testing.T) {
+	b := newBenchmark()
+
+	b.Prepare(func(_ context.Context, _ *BenchmarkStep) error {
+		return errors.New("prepare failed")
+	})
+
+	snapshot := b.Start(context.Background()).Snapshot()
+
+	if snapshot.Passed {
+		t.Fatal("aborted benchmark must not pass")
+	}
+
+	if len(snapshot.Phases) != 1 || snapshot.Phases[0].Status != string(PhaseAborted) {
+		t.Fatalf("%+v", snapshot.Phases)
+	}
+}
@@ -3,16 +3,128 @@ package isucandar
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/isucon/isucandar/failure"
 	"github.com/isucon/isucandar/score"
 )
 
+type BenchmarkStepOption func(*benchmarkStepEntry) error
+
+type benchmarkStepEntry struct {
+	name          string
+	f             BenchmarkStepFunc
+	timeout       time.Duration
+	attempts      int
+	retryInterval time.Duration
+}
+
+func newBenchmarkStepEntry(name string, f BenchmarkStepFunc, opts ...BenchmarkStepOption) (*benchmarkStepEntry, error) {
+	entry := &benchmarkStepEntry{
+		name:          name,
+		f:             f,
+		timeout:       time.Duration(0),
+		attempts:      1,
+		retryInterval: time.Duration(0),
+	}
+
+	for _, opt := range opts {
+		if err := opt(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, nil
+}
+
+func WithStepName(name string) BenchmarkStepOption {
+	return func(e *benchmarkStepEntry) error {
+		e.name = name
+		return nil
+	}
+}
+
+func WithStepTimeout(d time.Duration) BenchmarkStepOption {
+	return func(e *benchmarkStepEntry) error {
+		e.timeout = d
+		return nil
+	}
+}
+
+// attempts は初回実行を含めた最大実行回数です
+func WithStepRetry(attempts int, interval time.Duration) BenchmarkStepOption {
+	return func(e *benchmarkStepEntry) error {
+		if attempts < 1 {
+			attempts = 1
+		}
+		e.attempts = attempts
+		e.retryInterval = interval
+		return nil
+	}
+}
+
 type BenchmarkStep struct {
+	phase     string
 	errorCode failure.Code
 	mu        sync.RWMutex
 	result    *BenchmarkResult
 	cancel    context.CancelFunc
+	record    *BenchmarkStepResult
+	scenario  *BenchmarkScenarioResult
+	hooks     *benchmarkHooks
+	policy    *errorPolicyState
+
+	panicRecover bool
+	softDeadline <-chan struct{}
+	warmupUntil  time.Time
+}
+
+func (b *BenchmarkStep) child(record *BenchmarkStepResult) *BenchmarkStep {
+	child := b.clone()
+	child.record = record
+	return child
+}
+
+func (b *BenchmarkStep) scenarioChild(scenario *BenchmarkScenarioResult) *BenchmarkStep {
+	child := b.clone()
+	child.scenario = scenario
+	return child
+}
+
+func (b *BenchmarkStep) clone() *BenchmarkStep {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return &BenchmarkStep{
+		phase:     b.phase,
+		errorCode: b.errorCode,
+		mu:        sync.RWMutex{},
+		result:    b.result,
+		cancel:    b.cancel,
+		record:    b.record,
+		scenario:  b.scenario,
+		hooks:     b.hooks,
+		policy:    b.policy,
+
+		panicRecover: b.panicRecover,
+		softDeadline: b.softDeadline,
+		warmupUntil:  b.warmupUntil,
+	}
+}
+
+func (b *BenchmarkStep) setPhase(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.phase = name
+}
+
+func (b *BenchmarkStep) Phase() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.phase
 }
 
 func (b *BenchmarkStep) setErrorCode(code failure.Code) {
@@ -22,19 +134,104 @@ func (b *BenchmarkStep) setErrorCode(code failure.Code) {
 	b.errorCode = code
 }
 
+func (b *BenchmarkStep) setSoftDeadline(ch <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.softDeadline = ch
+}
+
+// SoftDeadline はフェーズの SoftTimeout を過ぎるか、フェーズが終了すると閉じられます。
+// 長時間かかる処理はこれを監視し、途中までの結果を記録して終了してください。
+func (b *BenchmarkStep) SoftDeadline() <-chan struct{} {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.softDeadline
+}
+
+func (b *BenchmarkStep) IsSoftDeadlineExceeded() bool {
+	select {
+	case <-b.SoftDeadline():
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *BenchmarkStep) setWarmup(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.warmupUntil = until
+}
+
+func (b *BenchmarkStep) IsWarmup() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.isWarmup()
+}
+
+func (b *BenchmarkStep) isWarmup() bool {
+	return !b.warmupUntil.IsZero() && time.Now().Before(b.warmupUntil)
+}
+
 func (b *BenchmarkStep) AddError(err error) {
+	b.addError(err, false)
+}
+
+// ステップ自体が返したエラーはウォームアップ中であっても記録します
+func (b *BenchmarkStep) addError(err error, force bool) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
+	if !force && b.isWarmup() {
+		return
+	}
+
 	if b.errorCode != nil {
-		b.result.Errors.Add(failure.NewError(b.errorCode, err))
-	} else {
-		b.result.Errors.Add(err)
+		err = failure.NewError(b.errorCode, err)
+	}
+
+	abort := ""
+	if b.policy != nil {
+		var record bool
+		if record, abort = b.policy.check(err); !record {
+			return
+		}
+	}
+
+	if b.record != nil {
+		atomic.AddInt64(&b.record.ErrorCount, 1)
+	}
+	if b.scenario != nil {
+		atomic.AddInt64(&b.scenario.ErrorCount, 1)
+	}
+
+	b.result.Errors.Add(err)
+
+	if abort != "" {
+		b.result.abort(abort)
+		b.cancel()
 	}
 }
 
 func (b *BenchmarkStep) AddScore(tag score.ScoreTag) {
+	if b.IsWarmup() {
+		return
+	}
+
 	b.result.Score.Add(tag)
+	if b.scenario != nil {
+		b.scenario.addScore(tag)
+	}
+
+	if b.hooks != nil {
+		for _, hook := range b.hooks.score {
+			hook(tag, b)
+		}
+	}
 }
 
 func (b *BenchmarkStep) Cancel() {
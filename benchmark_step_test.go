@@ -0,0 +1,127 @@
+package isucandar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/failure"
+)
+
+func findStepResult(result *BenchmarkResult, name string) *BenchmarkStepResult {
+	for _, step := range result.Steps() {
+		if step.Name == name {
+			return step
+		}
+	}
+	return nil
+}
+
+func TestBenchmarkStepResult(t *testing.T) {
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Prepare(func(_ context.Context, _ *BenchmarkStep) error {
+		return nil
+	}, WithStepName("initialize"))
+
+	b.Load(func(_ context.Context, s *BenchmarkStep) error {
+		s.AddError(errors.New("first"))
+		s.AddError(errors.New("second"))
+		return nil
+	})
+
+	b.Validation(func(ctx context.Context, _ *BenchmarkStep) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithStepName("hang"), WithStepTimeout(5*time.Millisecond))
+
+	b.Validation(func(_ context.Context, _ *BenchmarkStep) error {
+		return nil
+	}, WithStepName("after-hang"))
+
+	result := b.Start(context.Background())
+
+	if step := findStepResult(result, "initialize"); step == nil || step.Phase != PhasePrepare || step.Status != StepSucceeded || step.Attempts != 1 {
+		t.Fatalf("%+v", step)
+	}
+
+	if step := findStepResult(result, "load-1"); step == nil || step.Status != StepSucceeded || step.ErrorCount != 2 {
+		t.Fatalf("%+v", step)
+	}
+
+	if step := findStepResult(result, "hang"); step == nil || step.Status != StepTimedOut || step.ErrorCount != 1 || step.Duration < 5*time.Millisecond {
+		t.Fatalf("%+v", step)
+	}
+
+	if step := findStepResult(result, "after-hang"); step == nil || step.Status != StepSkipped {
+		t.Fatalf("%+v", step)
+	}
+
+	errs := result.Errors.All()
+	if len(errs) != 3 || !failure.IsCode(errs[2], ErrValidation) {
+		t.Fatal(errs)
+	}
+}
+
+func TestBenchmarkStepRetry(t *testing.T) {
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	b.Prepare(func(_ context.Context, _ *BenchmarkStep) error {
+		count++
+		if count < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	}, WithStepName("flaky"), WithStepRetry(3, time.Millisecond))
+
+	loaded := false
+	b.Load(func(_ context.Context, _ *BenchmarkStep) error {
+		loaded = true
+		return nil
+	})
+
+	result := b.Start(context.Background())
+
+	if len(result.Errors.All()) != 0 {
+		t.Fatal(result.Errors.All())
+	}
+
+	if step := findStepResult(result, "flaky"); step == nil || step.Status != StepSucceeded || step.Attempts != 3 {
+		t.Fatalf("%+v", step)
+	}
+
+	if !loaded {
+		t.Fatal("load not executed")
+	}
+}
+
+func TestBenchmarkStepRetryExhausted(t *testing.T) {
+	b, err := NewBenchmark()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	b.Prepare(func(_ context.Context, _ *BenchmarkStep) error {
+		count++
+		return errors.New("broken")
+	}, WithStepRetry(2, 0))
+
+	result := b.Start(context.Background())
+
+	if count != 2 || len(result.Errors.All()) != 1 {
+		t.Fatal(count, result.Errors.All())
+	}
+
+	if step := findStepResult(result, "prepare-1"); step == nil || step.Status != StepFailed || step.Attempts != 2 {
+		t.Fatalf("%+v", step)
+	}
+}
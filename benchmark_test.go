@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/isucon/isucandar/failure"
+	"github.com/isucon/isucandar/random"
 )
 
 var (
@@ -137,6 +138,31 @@ func TestBenchmarkPrepareTimeout(t *testing.T) {
 	}
 }
 
+func TestBenchmarkPrepareTimeoutPerStep(t *testing.T) {
+	b, err := NewBenchmark(WithPrepareTimeout(50 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// タイムアウトはフェーズ全体ではなく各ステップに適用されます
+	for i := 0; i < 3; i++ {
+		b.Prepare(func(ctx context.Context, _ *BenchmarkStep) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(30 * time.Millisecond):
+				return nil
+			}
+		})
+	}
+
+	result := b.Start(context.Background())
+
+	if len(result.Errors.All()) != 0 {
+		t.Fatal(result.Errors.All())
+	}
+}
+
 func TestBenchmarkPreparePanic(t *testing.T) {
 	ctx := context.TODO()
 	b := newBenchmark()
@@ -392,3 +418,114 @@ func TestBenchmarWithoutPanicRecover(t *testing.T) {
 		b.Start(ctx)
 	}()
 }
+
+func TestBenchmarkValidationTimeout(t *testing.T) {
+	ctx := context.TODO()
+	b := newBenchmark(WithValidationTimeout(5 * time.Millisecond))
+
+	b.Validation(func(ctx context.Context, _ *BenchmarkStep) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	result := b.Start(ctx)
+
+	if len(result.Errors.All()) != 1 || !failure.IsCode(result.Errors.All()[0], ErrValidation) || !failure.Is(result.Errors.All()[0], context.DeadlineExceeded) {
+		t.Fatal(result.Errors.All())
+	}
+}
+
+func TestBenchmarkValidationSoftTimeout(t *testing.T) {
+	ctx := context.TODO()
+	b := newBenchmark(WithValidationTimeout(1*time.Second), WithValidationSoftTimeout(5*time.Millisecond))
+
+	b.Validation(func(ctx context.Context, s *BenchmarkStep) error {
+		if s.IsSoftDeadlineExceeded() {
+			t.Fatal("soft deadline exceeded too early")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.SoftDeadline():
+			s.AddScore("partial")
+			return nil
+		}
+	})
+
+	result := b.Start(ctx)
+
+	if len(result.Errors.All()) != 0 {
+		t.Fatal(result.Errors.All())
+	}
+
+	if result.Score.Breakdown()["partial"] != 1 {
+		t.Fatal(result.Score.Breakdown())
+	}
+}
+
+func TestBenchmarkLoadWarmup(t *testing.T) {
+	ctx := context.TODO()
+	b := newBenchmark(WithLoadWarmup(20 * time.Millisecond))
+
+	b.Load(func(ctx context.Context, s *BenchmarkStep) error {
+		if !s.IsWarmup() {
+			t.Fatal("not in warmup")
+		}
+		s.AddScore("warmup")
+		s.AddError(errors.New("warmup"))
+
+		<-time.After(30 * time.Millisecond)
+
+		if s.IsWarmup() {
+			t.Fatal("still in warmup")
+		}
+		s.AddScore("load")
+		return nil
+	})
+
+	result := b.Start(ctx)
+
+	if len(result.Errors.All()) != 0 {
+		t.Fatal(result.Errors.All())
+	}
+
+	breakdown := result.Score.Breakdown()
+	if breakdown["warmup"] != 0 || breakdown["load"] != 1 {
+		t.Fatal(breakdown)
+	}
+}
+
+func TestBenchmarkSeed(t *testing.T) {
+	run := func(opts ...BenchmarkOption) (int64, []int64) {
+		b := newBenchmark(opts...)
+
+		values := make([]int64, 2)
+		b.Prepare(func(ctx context.Context, _ *BenchmarkStep) error {
+			values[0] = random.FromContext(ctx).Int63()
+			return nil
+		})
+		b.Validation(func(ctx context.Context, _ *BenchmarkStep) error {
+			values[1] = random.FromContext(ctx).Int63()
+			return nil
+		})
+
+		result := b.Start(context.TODO())
+		return result.Seed, values
+	}
+
+	seed1, values1 := run(WithSeed(42))
+	seed2, values2 := run(WithSeed(42))
+	if seed1 != 42 || seed2 != 42 {
+		t.Fatal(seed1, seed2)
+	}
+
+	if values1[0] != values2[0] || values1[1] != values2[1] || values1[0] == values1[1] {
+		t.Fatal(values1, values2)
+	}
+
+	seed3, values3 := run()
+	if seed3 == 42 || values3[0] == values1[0] {
+		t.Fatal(seed3, values3)
+	}
+}
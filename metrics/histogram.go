@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+const (
+	// 各桁を 2^subBucketBits 個に分割するため、相対誤差はおよそ 1/64 に収まります
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount >> 1
+)
+
+// Histogram は HDR Histogram と同様の対数線形バケットで時間を記録します。
+// 値の大きさに関わらず一定の相対精度でパーセンタイルを求められます。
+type Histogram struct {
+	mu     sync.RWMutex
+	counts []int64
+	count  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{
+		mu:     sync.RWMutex{},
+		counts: make([]int64, subBucketCount),
+		count:  0,
+		sum:    0,
+		min:    math.MaxInt64,
+		max:    0,
+	}
+}
+
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+	return subBucketCount + (shift-1)*subBucketHalf + int(v>>uint(shift)) - subBucketHalf
+}
+
+// バケットに含まれる最大の値を返します
+func bucketValue(index int) int64 {
+	if index < subBucketCount {
+		return int64(index)
+	}
+	shift := (index-subBucketCount)/subBucketHalf + 1
+	sub := int64((index-subBucketCount)%subBucketHalf + subBucketHalf)
+	return ((sub + 1) << uint(shift)) - 1
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+	index := bucketIndex(v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if index >= len(h.counts) {
+		counts := make([]int64, index+1)
+		copy(counts, h.counts)
+		h.counts = counts
+	}
+	h.counts[index]++
+	h.count++
+	h.sum += v
+	if v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+}
+
+func (h *Histogram) Merge(other *Histogram) {
+	other.mu.RLock()
+	counts := make([]int64, len(other.counts))
+	copy(counts, other.counts)
+	count, sum, min, max := other.count, other.sum, other.min, other.max
+	other.mu.RUnlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(counts) > len(h.counts) {
+		expanded := make([]int64, len(counts))
+		copy(expanded, h.counts)
+		h.counts = expanded
+	}
+	for i, c := range counts {
+		h.counts[i] += c
+	}
+	h.count += count
+	h.sum += sum
+	if min < h.min {
+		h.min = min
+	}
+	if max > h.max {
+		h.max = max
+	}
+}
+
+func (h *Histogram) Count() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.count
+}
+
+func (h *Histogram) Min() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.min)
+}
+
+func (h *Histogram) Max() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return time.Duration(h.max)
+}
+
+func (h *Histogram) Mean() time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / h.count)
+}
+
+// p は 0 から 100 の範囲で指定します
+func (h *Histogram) Percentile(p float64) time.Duration {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.percentile(p)
+}
+
+func (h *Histogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	if p > 100 {
+		p = 100
+	}
+
+	target := int64(math.Ceil(p / 100 * float64(h.count)))
+	if target < 1 {
+		target = 1
+	}
+
+	seen := int64(0)
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			v := bucketValue(i)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return time.Duration(v)
+		}
+	}
+	return time.Duration(h.max)
+}
+
+type HistogramSummary struct {
+	Count int64
+	Min   time.Duration
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+func (h *Histogram) Summary() HistogramSummary {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	summary := HistogramSummary{
+		Count: h.count,
+		P50:   h.percentile(50),
+		P90:   h.percentile(90),
+		P99:   h.percentile(99),
+		Max:   time.Duration(h.max),
+	}
+	if h.count > 0 {
+		summary.Min = time.Duration(h.min)
+		summary.Mean = time.Duration(h.sum / h.count)
+	}
+	return summary
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketIndex(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 129, 1000, 123456789, int64(time.Hour)} {
+		value := bucketValue(bucketIndex(v))
+		if value < v {
+			t.Fatalf("%d: %d", v, value)
+		}
+		if float64(value-v) > float64(v)/64+1 {
+			t.Fatalf("%d: %d", v, value)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram()
+
+	if s := h.Summary(); s.Count != 0 || s.P99 != 0 || s.Min != 0 {
+		t.Fatalf("%+v", s)
+	}
+
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	if h.Count() != 1000 || h.Min() != time.Millisecond || h.Max() != time.Second {
+		t.Fatal(h.Count(), h.Min(), h.Max())
+	}
+
+	if mean := h.Mean(); mean != 500500*time.Microsecond {
+		t.Fatal(mean)
+	}
+
+	for p, expected := range map[float64]time.Duration{
+		50:  500 * time.Millisecond,
+		90:  900 * time.Millisecond,
+		99:  990 * time.Millisecond,
+		100: time.Second,
+	} {
+		actual := h.Percentile(p)
+		if actual < expected || float64(actual-expected) > float64(expected)/64 {
+			t.Fatalf("p%.0f: %s", p, actual)
+		}
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a := NewHistogram()
+	b := NewHistogram()
+
+	a.Record(time.Millisecond)
+	b.Record(time.Hour)
+
+	a.Merge(b)
+
+	if a.Count() != 2 || a.Min() != time.Millisecond || a.Max() != time.Hour {
+		t.Fatal(a.Count(), a.Min(), a.Max())
+	}
+	if p := a.Percentile(100); p != time.Hour {
+		t.Fatal(p)
+	}
+}
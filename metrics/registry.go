@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Timing は1リクエストの各段階にかかった時間です。
+// 接続を再利用した場合など、発生しなかった段階は 0 になります。
+type Timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// リクエスト開始からレスポンスの最初の1バイトを受け取るまで
+	TTFB time.Duration
+	// リクエスト開始からレスポンスボディを読み終えるまで
+	Total time.Duration
+}
+
+type Endpoint struct {
+	Key     string
+	DNS     *Histogram
+	Connect *Histogram
+	TLS     *Histogram
+	TTFB    *Histogram
+	Total   *Histogram
+
+	errors  int64
+	retries int64
+}
+
+func newEndpoint(key string) *Endpoint {
+	return &Endpoint{
+		Key:     key,
+		DNS:     NewHistogram(),
+		Connect: NewHistogram(),
+		TLS:     NewHistogram(),
+		TTFB:    NewHistogram(),
+		Total:   NewHistogram(),
+		errors:  0,
+		retries: 0,
+	}
+}
+
+func (e *Endpoint) record(t Timing) {
+	if t.DNS > 0 {
+		e.DNS.Record(t.DNS)
+	}
+	if t.Connect > 0 {
+		e.Connect.Record(t.Connect)
+	}
+	if t.TLS > 0 {
+		e.TLS.Record(t.TLS)
+	}
+	e.TTFB.Record(t.TTFB)
+	e.Total.Record(t.Total)
+}
+
+func (e *Endpoint) Errors() int64 {
+	return atomic.LoadInt64(&e.errors)
+}
+
+func (e *Endpoint) Retries() int64 {
+	return atomic.LoadInt64(&e.retries)
+}
+
+type EndpointSummary struct {
+	Key     string
+	Count   int64
+	Errors  int64
+	Retries int64
+	DNS     HistogramSummary
+	Connect HistogramSummary
+	TLS     HistogramSummary
+	TTFB    HistogramSummary
+	Total   HistogramSummary
+}
+
+func (e *Endpoint) Summary() EndpointSummary {
+	total := e.Total.Summary()
+	return EndpointSummary{
+		Key:     e.Key,
+		Count:   total.Count,
+		Errors:  e.Errors(),
+		Retries: e.Retries(),
+		DNS:     e.DNS.Summary(),
+		Connect: e.Connect.Summary(),
+		TLS:     e.TLS.Summary(),
+		TTFB:    e.TTFB.Summary(),
+		Total:   total,
+	}
+}
+
+// Registry は "GET /items/:id" のようなキーごとにリクエストの時間を集計します
+type Registry struct {
+	mu        sync.RWMutex
+	endpoints map[string]*Endpoint
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		mu:        sync.RWMutex{},
+		endpoints: make(map[string]*Endpoint),
+	}
+}
+
+func (r *Registry) endpoint(key string) *Endpoint {
+	r.mu.RLock()
+	e, found := r.endpoints[key]
+	r.mu.RUnlock()
+	if found {
+		return e
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e, found = r.endpoints[key]; !found {
+		e = newEndpoint(key)
+		r.endpoints[key] = e
+	}
+	return e
+}
+
+func (r *Registry) Record(key string, t Timing) {
+	r.endpoint(key).record(t)
+}
+
+func (r *Registry) RecordError(key string) {
+	atomic.AddInt64(&r.endpoint(key).errors, 1)
+}
+
+func (r *Registry) RecordRetry(key string) {
+	atomic.AddInt64(&r.endpoint(key).retries, 1)
+}
+
+func (r *Registry) Endpoint(key string) (*Endpoint, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, found := r.endpoints[key]
+	return e, found
+}
+
+func (r *Registry) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0, len(r.endpoints))
+	for key := range r.endpoints {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// キーの昇順に並べた集計結果を返します
+func (r *Registry) Summary() []EndpointSummary {
+	keys := r.Keys()
+	summaries := make([]EndpointSummary, 0, len(keys))
+	for _, key := range keys {
+		if e, found := r.Endpoint(key); found {
+			summaries = append(summaries, e.Summary())
+		}
+	}
+	return summaries
+}
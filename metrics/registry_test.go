@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	r.Record("GET /b", Timing{TTFB: time.Millisecond, Total: 2 * time.Millisecond})
+	r.Record("GET /a", Timing{DNS: time.Millisecond, Connect: time.Millisecond, TTFB: time.Millisecond, Total: 3 * time.Millisecond})
+	r.Record("GET /a", Timing{TTFB: time.Millisecond, Total: 5 * time.Millisecond})
+	r.RecordError("GET /a")
+
+	summary := r.Summary()
+	if len(summary) != 2 || summary[0].Key != "GET /a" || summary[1].Key != "GET /b" {
+		t.Fatalf("%+v", summary)
+	}
+
+	a := summary[0]
+	if a.Count != 2 || a.Errors != 1 || a.DNS.Count != 1 || a.TLS.Count != 0 || a.Total.Max != 5*time.Millisecond {
+		t.Fatalf("%+v", a)
+	}
+
+	if _, found := r.Endpoint("GET /c"); found {
+		t.Fatal("unknown endpoint found")
+	}
+}
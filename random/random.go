@@ -0,0 +1,96 @@
+package random
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type contextKey struct{}
+
+type seeded struct {
+	seed int64
+	rand *rand.Rand
+}
+
+var (
+	globalRand = New(time.Now().UnixNano())
+)
+
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.src.Seed(seed)
+}
+
+// New は goroutine safe な *rand.Rand を作成します
+func New(seed int64) *rand.Rand {
+	return rand.New(&lockedSource{
+		mu:  sync.Mutex{},
+		src: rand.NewSource(seed).(rand.Source64),
+	})
+}
+
+func WithSeed(ctx context.Context, seed int64) context.Context {
+	return context.WithValue(ctx, contextKey{}, &seeded{
+		seed: seed,
+		rand: New(seed),
+	})
+}
+
+func Seed(ctx context.Context) (int64, bool) {
+	if s, ok := ctx.Value(contextKey{}).(*seeded); ok {
+		return s.seed, true
+	}
+	return 0, false
+}
+
+// FromContext は Context に設定された seed 付きの *rand.Rand を返します。
+// seed が設定されていない場合は共有の *rand.Rand を返します。
+func FromContext(ctx context.Context) *rand.Rand {
+	if s, ok := ctx.Value(contextKey{}).(*seeded); ok {
+		return s.rand
+	}
+	return globalRand
+}
+
+// Derive は seed と key から新しい seed を決定的に導出します
+func Derive(seed int64, key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatInt(seed, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// WithDerivedSeed は Context に seed が設定されている場合のみ、key から導出した seed を設定します。
+// 並列に実行される処理ごとに独立した乱数列を与えるために利用します。
+func WithDerivedSeed(ctx context.Context, key string) context.Context {
+	if seed, ok := Seed(ctx); ok {
+		return WithSeed(ctx, Derive(seed, key))
+	}
+	return ctx
+}
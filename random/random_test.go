@@ -0,0 +1,47 @@
+package random
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	ctx := context.Background()
+
+	if _, ok := Seed(ctx); ok {
+		t.Fatal("seed must not be set")
+	}
+
+	if FromContext(ctx) != globalRand {
+		t.Fatal("global rand not returned")
+	}
+
+	a := FromContext(WithSeed(ctx, 42))
+	b := FromContext(WithSeed(ctx, 42))
+	for i := 0; i < 100; i++ {
+		if a.Int63() != b.Int63() {
+			t.Fatal("not deterministic")
+		}
+	}
+
+	if seed, ok := Seed(WithSeed(ctx, 42)); !ok || seed != 42 {
+		t.Fatal(seed)
+	}
+}
+
+func TestWithDerivedSeed(t *testing.T) {
+	ctx := context.Background()
+
+	if WithDerivedSeed(ctx, "key") != ctx {
+		t.Fatal("derived without seed")
+	}
+
+	seeded := WithSeed(ctx, 1)
+	x, _ := Seed(WithDerivedSeed(seeded, "x"))
+	y, _ := Seed(WithDerivedSeed(seeded, "y"))
+	x2, _ := Seed(WithDerivedSeed(seeded, "x"))
+
+	if x == y || x != x2 || x == 1 {
+		t.Fatal(x, y, x2)
+	}
+}
@@ -2,7 +2,6 @@ package useragent
 
 import (
 	"fmt"
-	"math/rand"
 )
 
 var (
@@ -23,7 +22,11 @@ var (
 )
 
 func Chrome() string {
-	return fmt.Sprintf("Mozilla/5.0 (%s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36", Platform(), chromeVersions[rand.Intn(len(chromeVersions))])
+	return defaultGenerator.Chrome()
+}
+
+func (g *Generator) Chrome() string {
+	return fmt.Sprintf("Mozilla/5.0 (%s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36", g.Platform(), chromeVersions[g.rand.Intn(len(chromeVersions))])
 }
 
 var (
@@ -38,9 +41,17 @@ var (
 )
 
 func Edge() string {
-	return fmt.Sprintf("%s Edg/%s", Chrome(), edgeVersions[rand.Intn(len(edgeVersions))])
+	return defaultGenerator.Edge()
+}
+
+func (g *Generator) Edge() string {
+	return fmt.Sprintf("%s Edg/%s", g.Chrome(), edgeVersions[g.rand.Intn(len(edgeVersions))])
 }
 
 func Firefox() string {
-	return fmt.Sprintf("Mozilla/5.0 (%s) Gecko/20100101 Firefox/%d.0", Platform(), 70+rand.Intn(10))
+	return defaultGenerator.Firefox()
+}
+
+func (g *Generator) Firefox() string {
+	return fmt.Sprintf("Mozilla/5.0 (%s) Gecko/20100101 Firefox/%d.0", g.Platform(), 70+g.rand.Intn(10))
 }
@@ -0,0 +1,38 @@
+package useragent
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/isucon/isucandar/random"
+)
+
+type intner interface {
+	Intn(int) int
+}
+
+type globalIntner struct{}
+
+func (globalIntner) Intn(n int) int {
+	return rand.Intn(n)
+}
+
+type Generator struct {
+	rand intner
+}
+
+var (
+	defaultGenerator = &Generator{rand: globalIntner{}}
+)
+
+func NewGenerator(r *rand.Rand) *Generator {
+	return &Generator{rand: r}
+}
+
+// FromContext は Context に設定された seed に従って User-Agent を生成する Generator を返します
+func FromContext(ctx context.Context) *Generator {
+	if _, ok := random.Seed(ctx); ok {
+		return NewGenerator(random.FromContext(ctx))
+	}
+	return defaultGenerator
+}
@@ -2,26 +2,37 @@ package useragent
 
 import (
 	"fmt"
-	"math/rand"
 )
 
 func Platform() string {
-	switch rand.Intn(3) {
+	return defaultGenerator.Platform()
+}
+
+func (g *Generator) Platform() string {
+	switch g.rand.Intn(3) {
 	case 1:
-		return MacOS()
+		return g.MacOS()
 	case 2:
-		return Linux()
+		return g.Linux()
 	default:
-		return Windows()
+		return g.Windows()
 	}
 }
 
 func Windows() string {
+	return defaultGenerator.Windows()
+}
+
+func (g *Generator) Windows() string {
 	return "Windows NT 10.0; Win64; x64"
 }
 
 func MacOS() string {
-	return fmt.Sprintf("Macintosh; Intel Mac OS X 10.%d", 11+rand.Intn(3))
+	return defaultGenerator.MacOS()
+}
+
+func (g *Generator) MacOS() string {
+	return fmt.Sprintf("Macintosh; Intel Mac OS X 10.%d", 11+g.rand.Intn(3))
 }
 
 var (
@@ -33,5 +44,9 @@ var (
 )
 
 func Linux() string {
-	return fmt.Sprintf("X11; %s; Linux x86_64", linuxDistributions[rand.Intn(len(linuxDistributions))])
+	return defaultGenerator.Linux()
+}
+
+func (g *Generator) Linux() string {
+	return fmt.Sprintf("X11; %s; Linux x86_64", linuxDistributions[g.rand.Intn(len(linuxDistributions))])
 }
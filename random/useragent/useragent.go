@@ -1,16 +1,16 @@
 package useragent
 
-import (
-	"math/rand"
-)
-
 func UserAgent() string {
-	switch rand.Intn(3) {
+	return defaultGenerator.UserAgent()
+}
+
+func (g *Generator) UserAgent() string {
+	switch g.rand.Intn(3) {
 	case 1:
-		return Chrome()
+		return g.Chrome()
 	case 2:
-		return Edge()
+		return g.Edge()
 	default:
-		return Firefox()
+		return g.Firefox()
 	}
 }
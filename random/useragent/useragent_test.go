@@ -1,7 +1,10 @@
 package useragent
 
 import (
+	"context"
 	"testing"
+
+	"github.com/isucon/isucandar/random"
 )
 
 func TestUserAgent(t *testing.T) {
@@ -12,3 +15,17 @@ func TestUserAgent(t *testing.T) {
 		}
 	}
 }
+
+func TestGeneratorFromContext(t *testing.T) {
+	if FromContext(context.Background()) != defaultGenerator {
+		t.Fatal("default generator not returned")
+	}
+
+	for i := 0; i < 10; i++ {
+		a := FromContext(random.WithSeed(context.Background(), int64(i))).UserAgent()
+		b := FromContext(random.WithSeed(context.Background(), int64(i))).UserAgent()
+		if a != b {
+			t.Fatalf("not deterministic: %s, %s", a, b)
+		}
+	}
+}
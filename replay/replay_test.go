@@ -0,0 +1,258 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucandar/failure"
+)
+
+var pngBody = []byte{0x89, 0x50, 0x4e, 0x47, 0xff, 0xfe, 0x00, 0x01}
+
+func newReplayServer(counter *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(counter, 1)
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(body)
+		case "/cacheable":
+			w.Header().Set("Cache-Control", "max-age=100")
+			w.Header().Set("ETag", `"cacheable"`)
+			if r.Header.Get("If-None-Match") == `"cacheable"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			io.WriteString(w, "cacheable")
+		case "/png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngBody)
+		case "/time":
+			io.WriteString(w, time.Now().String())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestReplayHAR(t *testing.T) {
+	count := int32(0)
+	srv := newReplayServer(&count)
+	defer srv.Close()
+
+	recorder := agent.NewRecorder()
+	a, err := agent.NewAgent(agent.WithBaseURL(srv.URL), agent.WithRecorder(recorder), agent.WithNoCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/echo", "/time", "/missing"} {
+		req, _ := a.POST(path, strings.NewReader("body of "+path))
+		res, err := a.Do(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	buf := &bytes.Buffer{}
+	if err := recorder.WriteHAR(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := ReadHAR(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 3 || requests[0].Offset != 0 || requests[2].Status != http.StatusNotFound || string(requests[0].Body) != "body of /echo" {
+		t.Fatalf("%+v", requests)
+	}
+
+	// 別のサーバーに向けて再送する
+	replayCount := int32(0)
+	target := newReplayServer(&replayCount)
+	defer target.Close()
+
+	b, err := agent.NewAgent(agent.WithBaseURL(target.URL), agent.WithNoCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := NewReplayer(b, WithSpeed(10), WithBodyCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := replayer.Replay(context.Background(), requests)
+	if len(results) != 3 || replayCount != 3 {
+		t.Fatal(len(results), replayCount)
+	}
+
+	if results[0].Err != nil || results[0].StatusCode != http.StatusOK {
+		t.Fatalf("%+v", results[0])
+	}
+	if !failure.IsCode(results[1].Err, ErrChecksumMismatch) {
+		t.Fatalf("%+v", results[1])
+	}
+	if results[2].Err != nil || results[2].StatusCode != http.StatusNotFound {
+		t.Fatalf("%+v", results[2])
+	}
+}
+
+func TestReplayJSONL(t *testing.T) {
+	count := int32(0)
+	srv := newReplayServer(&count)
+	defer srv.Close()
+
+	log := strings.Join([]string{
+		`{"started_at":"2020-09-12T10:00:00.050Z","method":"POST","url":"/echo","body":"hello","status":200,"body_sha256":"` + Checksum([]byte("hello")) + `"}`,
+		``,
+		`{"started_at":"2020-09-12T10:00:00Z","url":"/echo","headers":{"X-Test":"1"},"status":200}`,
+		`{"started_at":"2020-09-12T10:00:00.030Z","url":"/missing","status":200}`,
+	}, "\n")
+
+	requests, err := ReadJSONL(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 3 || requests[0].Method != http.MethodGet || requests[0].Header.Get("X-Test") != "1" || requests[2].Offset != 50*time.Millisecond {
+		t.Fatalf("%+v", requests)
+	}
+
+	a, err := agent.NewAgent(agent.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := NewReplayer(a, WithBodyCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	results := replayer.Replay(context.Background(), requests)
+	if elapsed := time.Since(started); elapsed < 50*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Fatalf("%+v %+v", results[0], results[2])
+	}
+	if !failure.IsCode(results[1].Err, ErrStatusMismatch) || results[1].StatusCode != http.StatusNotFound {
+		t.Fatalf("%+v", results[1])
+	}
+
+	if _, err := ReadJSONL(strings.NewReader("{broken")); err == nil {
+		t.Fatal("broken line accepted")
+	}
+}
+
+func TestReplayCanceled(t *testing.T) {
+	count := int32(0)
+	srv := newReplayServer(&count)
+	defer srv.Close()
+
+	a, _ := agent.NewAgent(agent.WithBaseURL(srv.URL))
+	replayer, _ := NewReplayer(a)
+
+	requests := []*Request{
+		{Offset: 0, Method: http.MethodGet, URL: "/echo"},
+		{Offset: time.Hour, Method: http.MethodGet, URL: "/echo"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	results := replayer.Replay(ctx, requests)
+	if results[0].Err != nil || !failure.IsCode(results[1].Err, failure.CanceledErrorCode) || count != 1 {
+		t.Fatalf("%+v %+v %d", results[0], results[1], count)
+	}
+}
+
+func TestReplayDuplicatedURL(t *testing.T) {
+	count := int32(0)
+	srv := newReplayServer(&count)
+	defer srv.Close()
+
+	a, err := agent.NewAgent(agent.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := NewReplayer(a, WithoutTiming(), WithBodyCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []*Request{}
+	for i := 0; i < 3; i++ {
+		requests = append(requests, &Request{
+			Offset:     time.Duration(i) * time.Millisecond,
+			Method:     http.MethodGet,
+			URL:        "/cacheable",
+			Header:     http.Header{},
+			Status:     http.StatusOK,
+			BodySHA256: Checksum([]byte("cacheable")),
+		})
+	}
+
+	results := replayer.Replay(context.Background(), requests)
+	for _, result := range results {
+		if result.Err != nil || result.StatusCode != http.StatusOK {
+			t.Fatalf("%+v", result)
+		}
+	}
+	if count != 3 {
+		t.Fatal(count)
+	}
+}
+
+func TestReplayHARBinaryBody(t *testing.T) {
+	count := int32(0)
+	srv := newReplayServer(&count)
+	defer srv.Close()
+
+	recorder := agent.NewRecorder()
+	a, err := agent.NewAgent(agent.WithBaseURL(srv.URL), agent.WithRecorder(recorder), agent.WithNoCache())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := a.POST("/png", bytes.NewReader(pngBody))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res, err := a.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	buf := &bytes.Buffer{}
+	if err := recorder.WriteHAR(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := ReadHAR(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || !bytes.Equal(requests[0].Body, pngBody) || requests[0].BodySHA256 != Checksum(pngBody) {
+		t.Fatalf("%+v", requests)
+	}
+
+	replayer, err := NewReplayer(a, WithoutTiming(), WithBodyCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results := replayer.Replay(context.Background(), requests); results[0].Err != nil {
+		t.Fatalf("%+v", results[0])
+	}
+}
@@ -0,0 +1,212 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucandar/failure"
+)
+
+var (
+	ErrStatusMismatch   failure.StringCode = "replay-status"
+	ErrChecksumMismatch failure.StringCode = "replay-checksum"
+)
+
+var (
+	// Agent や http.Client が管理するため、記録されたものは送信しないヘッダーです
+	skipHeaders = map[string]bool{
+		"Host":              true,
+		"Cookie":            true,
+		"Content-Length":    true,
+		"Accept-Encoding":   true,
+		"Connection":        true,
+		"Keep-Alive":        true,
+		"Transfer-Encoding": true,
+		"If-Modified-Since": true,
+		"If-None-Match":     true,
+	}
+)
+
+type ReplayerOption func(*Replayer) error
+
+type Replayer struct {
+	Agent *agent.Agent
+	// 1 で記録された通りの間隔、2 で2倍の速さでリクエストを送ります。
+	// 0 の場合は間隔を空けずに1件ずつ順に送ります。
+	Speed float64
+	// true の場合、チェックサムが記録されているリクエストのボディを検証します
+	CheckBody bool
+}
+
+type Result struct {
+	Request    *Request
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+func NewReplayer(a *agent.Agent, opts ...ReplayerOption) (*Replayer, error) {
+	replayer := &Replayer{
+		Agent:     a,
+		Speed:     1,
+		CheckBody: false,
+	}
+
+	for _, opt := range opts {
+		if err := opt(replayer); err != nil {
+			return nil, err
+		}
+	}
+
+	return replayer, nil
+}
+
+func WithSpeed(speed float64) ReplayerOption {
+	return func(r *Replayer) error {
+		r.Speed = speed
+		return nil
+	}
+}
+
+func WithoutTiming() ReplayerOption {
+	return WithSpeed(0)
+}
+
+func WithBodyCheck() ReplayerOption {
+	return func(r *Replayer) error {
+		r.CheckBody = true
+		return nil
+	}
+}
+
+// requests を再送し、記録された順に結果を返します。
+// 元の間隔で送る場合、前のリクエストの応答を待たずに次のリクエストを送ります。
+func (r *Replayer) Replay(ctx context.Context, requests []*Request) []*Result {
+	results := make([]*Result, len(requests))
+
+	if r.Speed <= 0 {
+		for i, req := range requests {
+			if ctx.Err() != nil {
+				results[i] = &Result{Request: req, Err: failure.NewError(failure.CanceledErrorCode, ctx.Err())}
+				continue
+			}
+			results[i] = r.do(ctx, req)
+		}
+		return results
+	}
+
+	started := time.Now()
+	wg := &sync.WaitGroup{}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for i, req := range requests {
+		at := started.Add(time.Duration(float64(req.Offset) / r.Speed))
+		if wait := time.Until(at); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+
+		if ctx.Err() != nil {
+			results[i] = &Result{Request: req, Err: failure.NewError(failure.CanceledErrorCode, ctx.Err())}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, req *Request) {
+			defer wg.Done()
+			results[i] = r.do(ctx, req)
+		}(i, req)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (r *Replayer) do(ctx context.Context, req *Request) *Result {
+	result := &Result{Request: req}
+	started := time.Now()
+	defer func() {
+		result.Duration = time.Since(started)
+	}()
+
+	httpreq, err := r.newRequest(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	// 記録されたステータスと比較するため、キャッシュを使わずに毎回送信します
+	res, err := r.Agent.Do(ctx, httpreq, agent.WithRequestNoCache())
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.StatusCode = res.StatusCode
+	if req.Status != 0 && res.StatusCode != req.Status {
+		result.Err = failure.NewError(ErrStatusMismatch, fmt.Errorf("%s %s: expected %d, got %d", req.Method, httpreq.URL.Path, req.Status, res.StatusCode))
+		return result
+	}
+
+	if r.CheckBody && req.BodySHA256 != "" {
+		if sum := Checksum(body); sum != req.BodySHA256 {
+			result.Err = failure.NewError(ErrChecksumMismatch, fmt.Errorf("%s %s: body checksum mismatch", req.Method, httpreq.URL.Path))
+		}
+	}
+
+	return result
+}
+
+// Agent に BaseURL が設定されている場合、記録された URL のホストを置き換えます
+func (r *Replayer) newRequest(req *Request) (*http.Request, error) {
+	target := req.URL
+	if r.Agent.BaseURL != nil {
+		u, err := url.Parse(req.URL)
+		if err != nil {
+			return nil, err
+		}
+		target = u.RequestURI()
+	}
+
+	httpreq, err := r.Agent.NewRequest(req.Method, target, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Body) == 0 {
+		httpreq.Body = http.NoBody
+		httpreq.ContentLength = 0
+	}
+
+	for key, values := range req.Header {
+		key = http.CanonicalHeaderKey(key)
+		if skipHeaders[key] {
+			continue
+		}
+		httpreq.Header.Del(key)
+		for _, value := range values {
+			httpreq.Header.Add(key, value)
+		}
+	}
+
+	return httpreq, nil
+}
@@ -0,0 +1,170 @@
+package replay
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/isucon/isucandar/agent"
+)
+
+// Request は再送するリクエストと、期待するレスポンスです
+type Request struct {
+	// 最初のリクエストからの経過時間
+	Offset time.Duration
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+	// 0 の場合はステータスコードを検証しません
+	Status int
+	// 空の場合はボディを検証しません
+	BodySHA256 string
+}
+
+func Checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// HAR の各エントリを Request に変換します。
+// レスポンスボディが切り詰められずに記録されている場合のみ、チェックサムを設定します。
+func ReadHAR(r io.Reader) ([]*Request, error) {
+	har := &agent.HAR{}
+	if err := json.NewDecoder(r).Decode(har); err != nil {
+		return nil, err
+	}
+	if har.Log == nil {
+		return []*Request{}, nil
+	}
+
+	requests := make([]*Request, 0, len(har.Log.Entries))
+	for _, entry := range har.Log.Entries {
+		if entry.Request == nil {
+			continue
+		}
+
+		req := &Request{
+			Offset: entry.StartedDateTime.Sub(har.Log.Entries[0].StartedDateTime),
+			Method: entry.Request.Method,
+			URL:    entry.Request.URL,
+			Header: http.Header{},
+		}
+		for _, header := range entry.Request.Headers {
+			req.Header.Add(header.Name, header.Value)
+		}
+		if entry.Request.PostData != nil {
+			body, err := entry.Request.PostData.Body()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		if res := entry.Response; res != nil && res.Status > 0 {
+			req.Status = res.Status
+			if content := res.Content; content != nil && content.Text != "" {
+				body, err := content.Body()
+				if err != nil {
+					return nil, err
+				}
+				if content.Size == int64(len(body)) {
+					req.BodySHA256 = Checksum(body)
+				}
+			}
+		}
+
+		requests = append(requests, req)
+	}
+
+	normalizeOffsets(requests)
+	return requests, nil
+}
+
+// JSONL の1行です
+type jsonlRequest struct {
+	StartedAt  time.Time         `json:"started_at"`
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	Status     int               `json:"status"`
+	BodySHA256 string            `json:"body_sha256"`
+}
+
+// 1行に1つ、以下の形式の JSON で記録されたリクエストを読み込みます。
+//
+//	{"started_at":"2020-09-12T10:00:00Z","method":"GET","url":"/","headers":{"Accept":"*/*"},"body":"","status":200,"body_sha256":""}
+func ReadJSONL(r io.Reader) ([]*Request, error) {
+	requests := []*Request{}
+	startedAt := []time.Time{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		row := &jsonlRequest{}
+		if err := json.Unmarshal(line, row); err != nil {
+			return nil, err
+		}
+
+		method := row.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		req := &Request{
+			Method:     method,
+			URL:        row.URL,
+			Header:     http.Header{},
+			Status:     row.Status,
+			BodySHA256: row.BodySHA256,
+		}
+		for key, value := range row.Headers {
+			req.Header.Set(key, value)
+		}
+		if row.Body != "" {
+			req.Body = []byte(row.Body)
+		}
+
+		requests = append(requests, req)
+		startedAt = append(startedAt, row.StartedAt)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, req := range requests {
+		if !startedAt[i].IsZero() && !startedAt[0].IsZero() {
+			req.Offset = startedAt[i].Sub(startedAt[0])
+		}
+	}
+
+	normalizeOffsets(requests)
+	return requests, nil
+}
+
+// 最も早いリクエストを 0 として、開始順に並べ替えます
+func normalizeOffsets(requests []*Request) {
+	if len(requests) == 0 {
+		return
+	}
+
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].Offset < requests[j].Offset
+	})
+
+	base := requests[0].Offset
+	for _, req := range requests {
+		req.Offset -= base
+	}
+}
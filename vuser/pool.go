@@ -0,0 +1,210 @@
+package vuser
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/isucon/isucandar/agent"
+	"github.com/isucon/isucandar/random"
+	"github.com/isucon/isucandar/random/useragent"
+	"github.com/isucon/isucandar/worker"
+)
+
+type Lifecycle interface {
+	Login(context.Context, *User) error
+	Act(context.Context, *User) error
+	Logout(context.Context, *User) error
+}
+
+type AgentFactory func(context.Context) (*agent.Agent, error)
+type ErrorHandler func(error, *User)
+type PoolOption func(*Pool) error
+
+// Pool はワーカーの並列数と同じだけのユーザーを使い回しながら Lifecycle を実行します。
+// 各イテレーションでは待機中のユーザーを1人取り出し、未ログインなら Login を、
+// ログイン済みなら Act を実行し、ThinkTime だけ待ってからプールに戻します。
+type Pool struct {
+	lifecycle     Lifecycle
+	agentFactory  AgentFactory
+	thinkTime     ThinkTime
+	sessionLength int
+	errorHandler  ErrorHandler
+	workerOptions []worker.WorkerOption
+
+	mu     sync.Mutex
+	worker *worker.Worker
+	idle   []*User
+	users  []*User
+}
+
+func NewPool(lifecycle Lifecycle, opts ...PoolOption) (*Pool, error) {
+	pool := &Pool{
+		lifecycle:     lifecycle,
+		agentFactory:  defaultAgentFactory,
+		thinkTime:     NoThinkTime(),
+		sessionLength: 0,
+		errorHandler:  func(_ error, _ *User) {},
+		workerOptions: []worker.WorkerOption{worker.WithInfinityLoop(), worker.WithMaxParallelism(1)},
+		mu:            sync.Mutex{},
+		idle:          []*User{},
+		users:         []*User{},
+	}
+
+	for _, opt := range opts {
+		if err := opt(pool); err != nil {
+			return nil, err
+		}
+	}
+
+	w, err := worker.NewWorker(pool.iterate, pool.workerOptions...)
+	if err != nil {
+		return nil, err
+	}
+	pool.worker = w
+
+	return pool, nil
+}
+
+func defaultAgentFactory(ctx context.Context) (*agent.Agent, error) {
+	return agent.NewAgent(agent.WithUserAgent(useragent.FromContext(ctx).UserAgent()))
+}
+
+func WithAgentFactory(f AgentFactory) PoolOption {
+	return func(p *Pool) error {
+		p.agentFactory = f
+		return nil
+	}
+}
+
+func WithThinkTime(t ThinkTime) PoolOption {
+	return func(p *Pool) error {
+		p.thinkTime = t
+		return nil
+	}
+}
+
+// n 回 Act を実行したユーザーは Logout し、次のイテレーションで再度 Login します。
+// 0 以下の場合は Logout しません。
+func WithSessionLength(n int) PoolOption {
+	return func(p *Pool) error {
+		p.sessionLength = n
+		return nil
+	}
+}
+
+func WithErrorHandler(f ErrorHandler) PoolOption {
+	return func(p *Pool) error {
+		p.errorHandler = f
+		return nil
+	}
+}
+
+func WithWorkerOptions(opts ...worker.WorkerOption) PoolOption {
+	return func(p *Pool) error {
+		p.workerOptions = append(p.workerOptions, opts...)
+		return nil
+	}
+}
+
+func (p *Pool) Worker() *worker.Worker {
+	return p.worker
+}
+
+func (p *Pool) Process(ctx context.Context) {
+	p.worker.Process(ctx)
+}
+
+func (p *Pool) Users() []*User {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	users := make([]*User, len(p.users))
+	copy(users, p.users)
+	return users
+}
+
+func (p *Pool) acquire(ctx context.Context) (*User, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		user := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return user, nil
+	}
+	p.mu.Unlock()
+
+	a, err := p.agentFactory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		Agent: a,
+		Store: NewStore(),
+	}
+
+	p.mu.Lock()
+	user.ID = len(p.users)
+	p.users = append(p.users, user)
+	p.mu.Unlock()
+
+	return user, nil
+}
+
+func (p *Pool) release(user *User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.idle = append(p.idle, user)
+}
+
+func (p *Pool) iterate(ctx context.Context, _ int) {
+	user, err := p.acquire(ctx)
+	if err != nil {
+		p.errorHandler(err, nil)
+		p.think(ctx)
+		return
+	}
+	defer p.release(user)
+
+	if !user.loggedIn {
+		// ログインに失敗し続ける場合も、間隔を空けずに再試行しないよう思考時間を挟みます
+		if err := p.lifecycle.Login(ctx, user); err != nil {
+			p.errorHandler(err, user)
+			p.think(ctx)
+			return
+		}
+		user.loggedIn = true
+		user.actions = 0
+	} else {
+		if err := p.lifecycle.Act(ctx, user); err != nil {
+			p.errorHandler(err, user)
+		}
+		user.actions++
+
+		if p.sessionLength > 0 && user.actions >= p.sessionLength {
+			if err := p.lifecycle.Logout(ctx, user); err != nil {
+				p.errorHandler(err, user)
+			}
+			user.loggedIn = false
+		}
+	}
+
+	p.think(ctx)
+}
+
+func (p *Pool) think(ctx context.Context) {
+	d := p.thinkTime.Duration(random.FromContext(ctx))
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
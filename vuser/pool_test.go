@@ -0,0 +1,172 @@
+package vuser
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/isucon/isucandar/worker"
+)
+
+type countLifecycle struct {
+	login  int32
+	act    int32
+	logout int32
+	fail   bool
+}
+
+func (l *countLifecycle) Login(_ context.Context, u *User) error {
+	atomic.AddInt32(&l.login, 1)
+	if l.fail {
+		return errors.New("login failed")
+	}
+	u.Store.Set("token", u.ID)
+	return nil
+}
+
+func (l *countLifecycle) Act(_ context.Context, u *User) error {
+	atomic.AddInt32(&l.act, 1)
+	if _, ok := u.Store.Get("token"); !ok {
+		return errors.New("not logged in")
+	}
+	return nil
+}
+
+func (l *countLifecycle) Logout(_ context.Context, u *User) error {
+	atomic.AddInt32(&l.logout, 1)
+	u.Store.Delete("token")
+	return nil
+}
+
+func TestPool(t *testing.T) {
+	lifecycle := &countLifecycle{}
+	mu := sync.Mutex{}
+	errs := []error{}
+
+	pool, err := NewPool(
+		lifecycle,
+		WithSessionLength(2),
+		WithErrorHandler(func(err error, _ *User) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		}),
+		WithWorkerOptions(worker.WithLoopCount(9), worker.WithMaxParallelism(1)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool.Process(context.Background())
+
+	// login, act, act(logout) を3セッション
+	if lifecycle.login != 3 || lifecycle.act != 6 || lifecycle.logout != 3 {
+		t.Fatal(lifecycle.login, lifecycle.act, lifecycle.logout)
+	}
+
+	users := pool.Users()
+	if len(users) != 1 || users[0].Agent == nil || users[0].LoggedIn() {
+		t.Fatal(users)
+	}
+
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+}
+
+func TestPoolParallelism(t *testing.T) {
+	lifecycle := &countLifecycle{}
+	pool, err := NewPool(
+		lifecycle,
+		WithThinkTime(ConstantThinkTime(time.Millisecond)),
+		WithWorkerOptions(worker.WithLoopCount(40), worker.WithMaxParallelism(4)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool.Process(context.Background())
+
+	users := pool.Users()
+	if len(users) != 4 {
+		t.Fatal(len(users))
+	}
+
+	agents := map[interface{}]bool{}
+	for _, u := range users {
+		agents[u.Agent] = true
+	}
+	if len(agents) != 4 {
+		t.Fatal("agents are shared between users")
+	}
+
+	if lifecycle.login != 4 || lifecycle.act != 36 {
+		t.Fatal(lifecycle.login, lifecycle.act)
+	}
+}
+
+func TestPoolLoginFailure(t *testing.T) {
+	lifecycle := &countLifecycle{fail: true}
+	errCount := int32(0)
+	pool, err := NewPool(
+		lifecycle,
+		WithErrorHandler(func(_ error, _ *User) {
+			atomic.AddInt32(&errCount, 1)
+		}),
+		WithWorkerOptions(worker.WithLoopCount(3)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool.Process(context.Background())
+
+	if lifecycle.login != 3 || lifecycle.act != 0 || errCount != 3 {
+		t.Fatal(lifecycle.login, lifecycle.act, errCount)
+	}
+}
+
+func TestPoolLoginFailureThinkTime(t *testing.T) {
+	lifecycle := &countLifecycle{fail: true}
+	pool, err := NewPool(
+		lifecycle,
+		WithThinkTime(ConstantThinkTime(10*time.Millisecond)),
+		WithErrorHandler(func(_ error, _ *User) {}),
+		WithWorkerOptions(worker.WithInfinityLoop(), worker.WithMaxParallelism(1)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	pool.Process(ctx)
+
+	if login := atomic.LoadInt32(&lifecycle.login); login == 0 || login > 6 {
+		t.Fatal(login)
+	}
+}
+
+func TestThinkTime(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	if d := ConstantThinkTime(time.Second).Duration(r); d != time.Second {
+		t.Fatal(d)
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := UniformThinkTime(time.Millisecond, 2*time.Millisecond).Duration(r); d < time.Millisecond || d >= 2*time.Millisecond {
+			t.Fatal(d)
+		}
+		if d := ExponentialThinkTime(time.Millisecond).Duration(r); d < 0 {
+			t.Fatal(d)
+		}
+		if d := NormalThinkTime(time.Millisecond, 10*time.Millisecond).Duration(r); d < 0 {
+			t.Fatal(d)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package vuser
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ThinkTime はユーザーが次の操作を行うまでの待ち時間の分布です
+type ThinkTime interface {
+	Duration(r *rand.Rand) time.Duration
+}
+
+type ThinkTimeFunc func(r *rand.Rand) time.Duration
+
+func (f ThinkTimeFunc) Duration(r *rand.Rand) time.Duration {
+	return f(r)
+}
+
+func NoThinkTime() ThinkTime {
+	return ConstantThinkTime(0)
+}
+
+func ConstantThinkTime(d time.Duration) ThinkTime {
+	return ThinkTimeFunc(func(_ *rand.Rand) time.Duration {
+		return d
+	})
+}
+
+func UniformThinkTime(min, max time.Duration) ThinkTime {
+	return ThinkTimeFunc(func(r *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(r.Int63n(int64(max-min)))
+	})
+}
+
+func ExponentialThinkTime(mean time.Duration) ThinkTime {
+	return ThinkTimeFunc(func(r *rand.Rand) time.Duration {
+		return time.Duration(r.ExpFloat64() * float64(mean))
+	})
+}
+
+func NormalThinkTime(mean, stddev time.Duration) ThinkTime {
+	return ThinkTimeFunc(func(r *rand.Rand) time.Duration {
+		d := time.Duration(r.NormFloat64()*float64(stddev)) + mean
+		if d < 0 {
+			return 0
+		}
+		return d
+	})
+}
@@ -0,0 +1,66 @@
+package vuser
+
+import (
+	"sync"
+
+	"github.com/isucon/isucandar/agent"
+)
+
+type User struct {
+	ID    int
+	Agent *agent.Agent
+	Store *Store
+
+	loggedIn bool
+	actions  int
+}
+
+func (u *User) LoggedIn() bool {
+	return u.loggedIn
+}
+
+// Actions は現在のセッションで実行した Act の回数です
+func (u *User) Actions() int {
+	return u.actions
+}
+
+type Store struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+func NewStore() *Store {
+	return &Store{
+		mu:     sync.RWMutex{},
+		values: make(map[string]interface{}),
+	}
+}
+
+func (s *Store) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.values[key]
+	return v, ok
+}
+
+func (s *Store) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+}
+
+func (s *Store) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+}
+
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values = make(map[string]interface{})
+}
@@ -0,0 +1,225 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/isucon/isucandar/random"
+)
+
+var (
+	DefaultArrivalTolerance = 10 * time.Millisecond
+	// ArrivalProcess が 0 以下の間隔を返した場合は、この間隔として扱います
+	MinArrivalInterval = 1 * time.Millisecond
+
+	ErrInvalidArrivalRate = errors.New("arrival rate must be positive")
+)
+
+// ArrivalProcess は次のイテレーションを開始するまでの間隔を返します
+type ArrivalProcess interface {
+	Interval(r *rand.Rand) time.Duration
+}
+
+type ArrivalProcessFunc func(r *rand.Rand) time.Duration
+
+func (f ArrivalProcessFunc) Interval(r *rand.Rand) time.Duration {
+	return f(r)
+}
+
+// 毎秒 rate 回、等間隔にイテレーションを開始します
+func ConstantArrival(rate float64) ArrivalProcess {
+	interval := rateToInterval(rate)
+	return ArrivalProcessFunc(func(_ *rand.Rand) time.Duration {
+		return interval
+	})
+}
+
+// 平均して毎秒 rate 回、ポアソン過程に従ってイテレーションを開始します
+func PoissonArrival(rate float64) ArrivalProcess {
+	mean := rateToInterval(rate)
+	return ArrivalProcessFunc(func(r *rand.Rand) time.Duration {
+		return time.Duration(r.ExpFloat64() * float64(mean))
+	})
+}
+
+func rateToInterval(rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
+
+type ArrivalStats struct {
+	// 予定時刻に達したイテレーションの数
+	Scheduled int64
+	// 実際に開始したイテレーションの数
+	Dispatched int64
+	// 並列数の上限に達していたため開始しなかったイテレーションの数
+	Dropped int64
+	// 予定時刻から許容値を超えて遅れて開始したイテレーションの数
+	Late int64
+}
+
+type arrivalCounter struct {
+	scheduled  int64
+	dispatched int64
+	dropped    int64
+	late       int64
+}
+
+func (c *arrivalCounter) stats() ArrivalStats {
+	return ArrivalStats{
+		Scheduled:  atomic.LoadInt64(&c.scheduled),
+		Dispatched: atomic.LoadInt64(&c.dispatched),
+		Dropped:    atomic.LoadInt64(&c.dropped),
+		Late:       atomic.LoadInt64(&c.late),
+	}
+}
+
+type scheduledAtKey struct{}
+
+// オープンループで実行されたイテレーションの予定開始時刻を返します。
+// レイテンシをこの時刻から計測することで coordinated omission を避けられます。
+func ScheduledAt(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(scheduledAtKey{}).(time.Time)
+	return t, ok
+}
+
+func (w *Worker) ArrivalStats() ArrivalStats {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.arrivalCounter.stats()
+}
+
+// 応答時間に関わらず ArrivalProcess に従ってイテレーションを開始します。
+// 実行中のイテレーションが並列数の上限に達している場合、そのイテレーションは破棄されます。
+func (w *Worker) processArrival(ctx context.Context, limit int) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	w.mu.Lock()
+	arrival := w.arrival
+	tolerance := w.arrivalTolerance
+	w.parallel = nil
+	w.arrivalCounter = &arrivalCounter{}
+	counter := w.arrivalCounter
+	w.mu.Unlock()
+
+	started := time.Now()
+	w.applyProfile(ctx, started)
+
+	r := random.FromContext(ctx)
+	seeder := newIterationSeeder(ctx)
+	inflight := int32(0)
+	wg := &sync.WaitGroup{}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	next := started
+L:
+	for i := 0; limit < 1 || i < limit; i++ {
+		interval := arrival.Interval(r)
+		if interval <= 0 {
+			interval = MinArrivalInterval
+		}
+		next = next.Add(interval)
+		if wait := time.Until(next); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				break L
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			break L
+		}
+
+		atomic.AddInt64(&counter.scheduled, 1)
+		if time.Since(next) > tolerance {
+			atomic.AddInt64(&counter.late, 1)
+		}
+
+		parallelism := atomic.LoadInt32(&w.parallelism)
+		if parallelism > 0 && atomic.LoadInt32(&inflight) >= parallelism {
+			atomic.AddInt64(&counter.dropped, 1)
+			continue
+		}
+
+		atomic.AddInt32(&inflight, 1)
+		atomic.AddInt64(&counter.dispatched, 1)
+		wg.Add(1)
+
+		index := -1
+		if limit > 0 {
+			index = i
+		}
+		iterCtx := context.WithValue(seeder.seed(ctx, int64(i)), scheduledAtKey{}, next)
+		go func(ctx context.Context, index int) {
+			defer wg.Done()
+			defer atomic.AddInt32(&inflight, -1)
+			w.workFunc(ctx, index)
+		}(iterCtx, index)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+	case <-done:
+	}
+}
+
+func WithArrival(process ArrivalProcess) WorkerOption {
+	return func(w *Worker) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		w.arrival = process
+		return nil
+	}
+}
+
+func WithArrivalRate(rate float64) WorkerOption {
+	if rate <= 0 {
+		return func(_ *Worker) error {
+			return ErrInvalidArrivalRate
+		}
+	}
+	return WithArrival(ConstantArrival(rate))
+}
+
+func WithPoissonArrival(rate float64) WorkerOption {
+	if rate <= 0 {
+		return func(_ *Worker) error {
+			return ErrInvalidArrivalRate
+		}
+	}
+	return WithArrival(PoissonArrival(rate))
+}
+
+func WithArrivalTolerance(d time.Duration) WorkerOption {
+	return func(w *Worker) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		w.arrivalTolerance = d
+		return nil
+	}
+}
+
+// 空きが出た時点で次のイテレーションを開始する通常のモードに戻します
+func WithClosedLoop() WorkerOption {
+	return WithArrival(nil)
+}
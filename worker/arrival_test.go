@@ -0,0 +1,135 @@
+package worker
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestArrivalProcesses(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	if d := ConstantArrival(100).Interval(r); d != 10*time.Millisecond {
+		t.Fatal(d)
+	}
+
+	total := time.Duration(0)
+	for i := 0; i < 10000; i++ {
+		total += PoissonArrival(100).Interval(r)
+	}
+	if mean := total / 10000; mean < 9*time.Millisecond || mean > 11*time.Millisecond {
+		t.Fatal(mean)
+	}
+
+	if _, err := NewWorker(nil, WithArrivalRate(0)); err != ErrInvalidArrivalRate {
+		t.Fatal(err)
+	}
+	if _, err := NewWorker(nil, WithPoissonArrival(-1)); err != ErrInvalidArrivalRate {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkerArrivalRate(t *testing.T) {
+	count := int32(0)
+	scheduled := int32(0)
+	f := func(ctx context.Context, _ int) {
+		atomic.AddInt32(&count, 1)
+		if _, ok := ScheduledAt(ctx); ok {
+			atomic.AddInt32(&scheduled, 1)
+		}
+	}
+
+	worker, err := NewWorker(f, WithLoopCount(20), WithArrivalRate(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	worker.Process(context.Background())
+
+	if elapsed := time.Since(started); elapsed < 20*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+
+	if count != 20 || scheduled != 20 {
+		t.Fatal(count, scheduled)
+	}
+
+	stats := worker.ArrivalStats()
+	if stats.Scheduled != 20 || stats.Dispatched != 20 || stats.Dropped != 0 {
+		t.Fatalf("%+v", stats)
+	}
+}
+
+func TestWorkerArrivalDropped(t *testing.T) {
+	count := int32(0)
+	f := func(ctx context.Context, _ int) {
+		atomic.AddInt32(&count, 1)
+		select {
+		case <-ctx.Done():
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// 1ms 間隔で到着するが、処理に 50ms かかり並列数は 2 まで
+	worker, err := NewWorker(f, WithLoopCount(10), WithArrivalRate(1000), WithMaxParallelism(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worker.Process(context.Background())
+
+	stats := worker.ArrivalStats()
+	if stats.Scheduled != 10 || stats.Dispatched != 2 || stats.Dropped != 8 || count != 2 {
+		t.Fatalf("%+v %d", stats, count)
+	}
+}
+
+func TestWorkerPoissonArrivalCanceled(t *testing.T) {
+	count := int32(0)
+	f := func(_ context.Context, _ int) {
+		atomic.AddInt32(&count, 1)
+	}
+
+	worker, err := NewWorker(f, WithInfinityLoop(), WithPoissonArrival(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	worker.Process(ctx)
+
+	// 平均 50 回程度
+	if c := atomic.LoadInt32(&count); c < 10 || c > 150 {
+		t.Fatal(c)
+	}
+	if stats := worker.ArrivalStats(); stats.Dispatched != stats.Scheduled {
+		t.Fatalf("%+v", stats)
+	}
+}
+
+func TestWorkerArrivalZeroInterval(t *testing.T) {
+	f := func(_ context.Context, _ int) {}
+
+	zero := ArrivalProcessFunc(func(_ *rand.Rand) time.Duration {
+		return 0
+	})
+	worker, err := NewWorker(f, WithInfinityLoop(), WithArrival(zero))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	worker.Process(ctx)
+
+	// 0 以下の間隔は MinArrivalInterval に切り上げられます
+	if stats := worker.ArrivalStats(); stats.Scheduled == 0 || stats.Scheduled > 60 {
+		t.Fatalf("%+v", stats)
+	}
+}
@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+var (
+	DefaultProfileInterval = 100 * time.Millisecond
+)
+
+// Profile は開始からの経過時間に応じた並列数を返します
+// 0 以下の値は並列数無制限 (-1) として扱います
+type Profile interface {
+	Parallelism(elapsed time.Duration) int32
+}
+
+type ProfileFunc func(elapsed time.Duration) int32
+
+func (f ProfileFunc) Parallelism(elapsed time.Duration) int32 {
+	return f(elapsed)
+}
+
+func ConstantProfile(parallelism int32) Profile {
+	return ProfileFunc(func(_ time.Duration) int32 {
+		return parallelism
+	})
+}
+
+// from から to まで duration をかけて線形に並列数を変化させます
+func LinearRampProfile(from, to int32, duration time.Duration) Profile {
+	return ProfileFunc(func(elapsed time.Duration) int32 {
+		if duration <= 0 || elapsed >= duration {
+			return to
+		}
+		if elapsed <= 0 {
+			return from
+		}
+		return from + int32(float64(to-from)*float64(elapsed)/float64(duration))
+	})
+}
+
+type ProfileStage struct {
+	Duration    time.Duration
+	Parallelism int32
+}
+
+// 各 Stage を順に適用し、最後の Stage の並列数を維持します
+func StepProfile(stages ...ProfileStage) Profile {
+	return ProfileFunc(func(elapsed time.Duration) int32 {
+		parallelism := int32(1)
+		for _, stage := range stages {
+			parallelism = stage.Parallelism
+			if elapsed < stage.Duration {
+				break
+			}
+			elapsed -= stage.Duration
+		}
+		return parallelism
+	})
+}
+
+// at から duration の間だけ並列数を peak に引き上げます
+func SpikeProfile(base, peak int32, at, duration time.Duration) Profile {
+	return ProfileFunc(func(elapsed time.Duration) int32 {
+		if elapsed >= at && elapsed < at+duration {
+			return peak
+		}
+		return base
+	})
+}
+
+func (w *Worker) applyProfile(ctx context.Context, started time.Time) {
+	w.mu.RLock()
+	profile := w.profile
+	interval := w.profileInterval
+	w.mu.RUnlock()
+
+	if profile == nil {
+		return
+	}
+
+	current := int32(0)
+	apply := func() {
+		parallelism := profile.Parallelism(time.Since(started))
+		if parallelism < 1 {
+			parallelism = -1
+		}
+		if parallelism != current {
+			current = parallelism
+			w.SetParallelism(parallelism)
+		}
+	}
+	apply()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				apply()
+			}
+		}
+	}()
+}
+
+func WithProfile(profile Profile, interval time.Duration) WorkerOption {
+	return func(w *Worker) error {
+		if interval <= 0 {
+			interval = DefaultProfileInterval
+		}
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		w.profile = profile
+		w.profileInterval = interval
+		return nil
+	}
+}
@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProfiles(t *testing.T) {
+	constant := ConstantProfile(5)
+	if p := constant.Parallelism(time.Hour); p != 5 {
+		t.Fatalf("constant: %d", p)
+	}
+
+	ramp := LinearRampProfile(10, 20, 10*time.Second)
+	for elapsed, expected := range map[time.Duration]int32{
+		0:                10,
+		5 * time.Second:  15,
+		10 * time.Second: 20,
+		time.Minute:      20,
+	} {
+		if p := ramp.Parallelism(elapsed); p != expected {
+			t.Fatalf("ramp(%s): %d", elapsed, p)
+		}
+	}
+
+	step := StepProfile(
+		ProfileStage{Duration: time.Second, Parallelism: 1},
+		ProfileStage{Duration: time.Second, Parallelism: 3},
+		ProfileStage{Duration: time.Second, Parallelism: 5},
+	)
+	for elapsed, expected := range map[time.Duration]int32{
+		0:                       1,
+		1500 * time.Millisecond: 3,
+		2500 * time.Millisecond: 5,
+		time.Minute:             5,
+	} {
+		if p := step.Parallelism(elapsed); p != expected {
+			t.Fatalf("step(%s): %d", elapsed, p)
+		}
+	}
+
+	spike := SpikeProfile(2, 50, time.Second, time.Second)
+	for elapsed, expected := range map[time.Duration]int32{
+		0:                       2,
+		1500 * time.Millisecond: 50,
+		2 * time.Second:         2,
+	} {
+		if p := spike.Parallelism(elapsed); p != expected {
+			t.Fatalf("spike(%s): %d", elapsed, p)
+		}
+	}
+}
+
+func TestWorkerWithProfile(t *testing.T) {
+	current := int32(0)
+	peak := int32(0)
+	f := func(_ context.Context, _ int) {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+	}
+
+	profile := StepProfile(
+		ProfileStage{Duration: 30 * time.Millisecond, Parallelism: 1},
+		ProfileStage{Duration: time.Second, Parallelism: 4},
+	)
+
+	worker, err := NewWorker(f, WithInfinityLoop(), WithProfile(profile, 5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	worker.Process(ctx)
+
+	if p := atomic.LoadInt32(&peak); p != 1 {
+		t.Fatalf("parallelism exceeded in first stage: %d", p)
+	}
+
+	if p := atomic.LoadInt32(&worker.parallelism); p != 1 {
+		t.Fatalf("parallelism not applied: %d", p)
+	}
+}
+
+func TestWorkerWithUnlimitedProfile(t *testing.T) {
+	f := func(_ context.Context, _ int) {
+		time.Sleep(time.Millisecond)
+	}
+
+	worker, err := NewWorker(f, WithInfinityLoop(), WithMaxParallelism(1), WithProfile(ConstantProfile(0), 5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	worker.Process(ctx)
+
+	if p := atomic.LoadInt32(&worker.parallelism); p != -1 {
+		t.Fatalf("zero parallelism must be unlimited: %d", p)
+	}
+}
@@ -2,10 +2,13 @@ package worker
 
 import (
 	"context"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/isucon/isucandar/parallel"
+	"github.com/isucon/isucandar/random"
 )
 
 var (
@@ -21,6 +24,13 @@ type Worker struct {
 	count       int32
 	parallelism int32
 	parallel    *parallel.Parallel
+
+	profile         Profile
+	profileInterval time.Duration
+
+	arrival          ArrivalProcess
+	arrivalTolerance time.Duration
+	arrivalCounter   *arrivalCounter
 }
 
 func NewWorker(f WorkerFunc, opts ...WorkerOption) (*Worker, error) {
@@ -36,6 +46,9 @@ func NewWorker(f WorkerFunc, opts ...WorkerOption) (*Worker, error) {
 		workFunc:    f,
 		count:       count,
 		parallelism: parallelism,
+
+		arrivalTolerance: DefaultArrivalTolerance,
+		arrivalCounter:   &arrivalCounter{},
 	}
 
 	for _, opt := range opts {
@@ -49,8 +62,17 @@ func NewWorker(f WorkerFunc, opts ...WorkerOption) (*Worker, error) {
 }
 
 func (w *Worker) Process(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w.mu.RLock()
+	arrival := w.arrival
+	w.mu.RUnlock()
+
 	count := atomic.LoadInt32(&w.count)
-	if count < 1 {
+	if arrival != nil {
+		w.processArrival(ctx, int(count))
+	} else if count < 1 {
 		w.processInfinity(ctx)
 	} else {
 		w.processLimited(ctx, int(count))
@@ -67,9 +89,12 @@ func (w *Worker) processInfinity(ctx context.Context) {
 	w.mu.Lock()
 	w.parallel = parallel
 	w.mu.Unlock()
+	w.applyProfile(ctx, time.Now())
 
+	seeder := newIterationSeeder(ctx)
+	iteration := int64(-1)
 	work := func(ctx context.Context) {
-		w.workFunc(ctx, -1)
+		w.workFunc(seeder.seed(ctx, atomic.AddInt64(&iteration, 1)), -1)
 	}
 
 L:
@@ -95,10 +120,12 @@ func (w *Worker) processLimited(ctx context.Context, limit int) {
 	w.mu.Lock()
 	w.parallel = parallel
 	w.mu.Unlock()
+	w.applyProfile(ctx, time.Now())
 
+	seeder := newIterationSeeder(ctx)
 	work := func(i int) func(context.Context) {
 		return func(ctx context.Context) {
-			w.workFunc(ctx, i)
+			w.workFunc(seeder.seed(ctx, int64(i)), i)
 		}
 	}
 
@@ -115,6 +142,28 @@ L:
 	w.Wait()
 }
 
+// Context に seed が設定されている場合、各イテレーションに決定的な seed を与えます
+type iterationSeeder struct {
+	seeded bool
+	base   int64
+}
+
+func newIterationSeeder(ctx context.Context) *iterationSeeder {
+	seeder := &iterationSeeder{}
+	if _, ok := random.Seed(ctx); ok {
+		seeder.seeded = true
+		seeder.base = random.FromContext(ctx).Int63()
+	}
+	return seeder
+}
+
+func (s *iterationSeeder) seed(ctx context.Context, iteration int64) context.Context {
+	if !s.seeded {
+		return ctx
+	}
+	return random.WithSeed(ctx, random.Derive(s.base, strconv.FormatInt(iteration, 10)))
+}
+
 func (w *Worker) Wait() {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
@@ -7,6 +7,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/isucon/isucandar/random"
 )
 
 func TestWorker(t *testing.T) {
@@ -249,3 +251,31 @@ func BenchmarkWorker(b *testing.B) {
 	worker.Wait()
 	b.StopTimer()
 }
+
+func TestWorkerSeed(t *testing.T) {
+	run := func(ctx context.Context) []int64 {
+		values := make([]int64, 10)
+		f := func(ctx context.Context, i int) {
+			values[i] = random.FromContext(ctx).Int63()
+		}
+
+		worker, err := NewWorker(f, WithLoopCount(10), WithMaxParallelism(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		worker.Process(ctx)
+		return values
+	}
+
+	a := run(random.WithSeed(context.Background(), 1))
+	b := run(random.WithSeed(context.Background(), 1))
+
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal(a, b)
+		}
+		if i > 0 && a[i] == a[i-1] {
+			t.Fatal("iterations share the same seed")
+		}
+	}
+}